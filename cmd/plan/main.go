@@ -0,0 +1,172 @@
+// Command plan prints which workflows of a local repository would run for a push or pull request,
+// and which of their jobs and steps. Changed files for path filters are read from the local git
+// repository, nothing is fetched from GitHub.
+//
+//	plan -event pull_request -base main -head HEAD
+//	plan -event push -base HEAD~1
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/cschleiden/actionlint-interpreter/git"
+	"github.com/cschleiden/actionlint-interpreter/workflow"
+)
+
+func main() {
+	dir := flag.String("C", ".", "path of the repository")
+	eventName := flag.String("event", "pull_request", "event to simulate, push or pull_request")
+	base := flag.String("base", "", "base branch of the pull request, or the commit before the push. Defaults to main for pull requests and HEAD~1 for pushes")
+	head := flag.String("head", "HEAD", "head commit of the pull request or the push")
+	branch := flag.String("branch", "", "pushed branch, or head branch of the pull request. Defaults to the current branch")
+	action := flag.String("action", "opened", "activity type of the pull_request event")
+	runner := flag.String("runner", "", "runs-on label of the hosted image all jobs run on, like windows-latest. Defaults to the runs-on labels of each job")
+	actions := flag.String("actions", "", "directory of the metadata of remote actions, like actions/checkout@v4/action.yml")
+	flag.Parse()
+
+	if err := run(*dir, *eventName, *base, *head, *branch, *action, *runner, *actions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, eventName, base, head, branch, action, runner, actions string) error {
+	var profile *workflow.RunnerProfile
+	if runner != "" {
+		profile = workflow.LookupRunnerProfile(runner)
+		if profile == nil {
+			return fmt.Errorf("unknown runner %q", runner)
+		}
+	}
+
+	var catalog *workflow.Catalog
+	if actions != "" {
+		catalog = workflow.NewCatalog(actions)
+	}
+
+	if branch == "" {
+		b, err := git.CurrentBranch(dir)
+		if err != nil {
+			return err
+		}
+		branch = b
+	}
+
+	headSHA, err := git.ResolveCommit(dir, head)
+	if err != nil {
+		return err
+	}
+
+	var event *workflow.Event
+
+	switch eventName {
+	case "push":
+		if base == "" {
+			base = "HEAD~1"
+		}
+
+		baseSHA, err := git.ResolveCommit(dir, base)
+		if err != nil {
+			return err
+		}
+
+		files, err := git.ChangedFilesPush(dir, baseSHA, headSHA)
+		if err != nil {
+			return err
+		}
+
+		ref := "refs/heads/" + branch
+		event = &workflow.Event{
+			Name:         "push",
+			Payload:      expr.ContextData{"ref": ref, "before": baseSHA, "after": headSHA},
+			ChangedFiles: files,
+		}
+
+	case "pull_request":
+		if base == "" {
+			base = "main"
+		}
+
+		baseSHA, err := git.ResolveCommit(dir, base)
+		if err != nil {
+			return err
+		}
+
+		files, err := git.ChangedFilesPullRequest(dir, baseSHA, headSHA)
+		if err != nil {
+			return err
+		}
+
+		baseBranch := strings.TrimPrefix(base, "origin/")
+		event = &workflow.Event{
+			Name: "pull_request",
+			Payload: expr.ContextData{
+				"action": action,
+				"pull_request": expr.ContextData{
+					"base": expr.ContextData{"ref": baseBranch},
+					"head": expr.ContextData{"ref": branch, "sha": headSHA},
+				},
+			},
+			ChangedFiles: files,
+		}
+
+	default:
+		return fmt.Errorf("unsupported event %q, use push or pull_request", eventName)
+	}
+
+	github := workflow.GitHubContext(event, workflow.Repository{}, workflow.Run{})
+
+	files, err := workflow.LoadDir(filepath.Join(dir, ".github", "workflows"))
+	if err != nil {
+		return err
+	}
+
+	// A workflow that can't be parsed or simulated doesn't stop the others from being planned
+	failed := 0
+	for _, f := range files {
+		if f.Err != nil {
+			fmt.Printf("%s: error: %v\n", f.Path, f.Err)
+			failed++
+			continue
+		}
+
+		m, err := workflow.MatchTrigger(f.Workflow, event)
+		if err != nil {
+			fmt.Printf("%s: error: %v\n", f.Path, err)
+			failed++
+			continue
+		}
+
+		reason := ""
+		if m.Reason != "" {
+			reason = " (" + m.Reason + ")"
+		}
+		fmt.Printf("%s: %s%s\n", f.Path, m.Status, reason)
+
+		if m.Status == workflow.StatusSkipped {
+			continue
+		}
+
+		plan, err := workflow.Simulate(f.Workflow, event, workflow.Options{Contexts: expr.ContextData{"github": github}, RunnerProfile: profile, Dir: dir, Catalog: catalog})
+		if err != nil {
+			fmt.Printf("  error: %v\n", err)
+			failed++
+			continue
+		}
+
+		for _, l := range strings.Split(strings.TrimSuffix(plan.String(), "\n"), "\n") {
+			fmt.Println("  " + l)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workflows could not be planned", failed, len(files))
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// Command route prints the cascade of workflows started by an event: the workflows triggered by
+// the event, and the workflows they start in turn through `workflow_run`, `workflow_call`, and
+// dispatch events.
+//
+//	route -event push -payload push.json
+//	route -event workflow_dispatch -depth 5
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/cschleiden/actionlint-interpreter/workflow"
+)
+
+func main() {
+	dir := flag.String("dir", ".github/workflows", "directory of the workflow files")
+	eventName := flag.String("event", "push", "name of the event")
+	payload := flag.String("payload", "", "path of a JSON file with the webhook payload of the event")
+	depth := flag.Int("depth", workflow.DefaultMaxDepth, "maximum depth of the cascade")
+	flag.Parse()
+
+	if err := run(*dir, *eventName, *payload, *depth); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, eventName, payload string, depth int) error {
+	event := &workflow.Event{Name: eventName, Payload: expr.ContextData{}}
+	if payload != "" {
+		e, err := workflow.LoadEvent(eventName, payload)
+		if err != nil {
+			return err
+		}
+		event = e
+	}
+
+	files, err := workflow.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.Err != nil {
+			fmt.Fprintln(os.Stderr, f.Err)
+		}
+	}
+
+	nodes, err := workflow.Route(files, event, workflow.RouteOptions{MaxDepth: depth})
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(workflow.RouteString(nodes))
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rhysd/actionlint"
+)
+
+// Availability lists the contexts and special functions available to expressions at a workflow
+// key. Functions not listed in the availability table, like `contains` or `format`, are available
+// everywhere.
+type Availability struct {
+	Contexts  []string
+	Functions []string
+}
+
+// specialFunctions are the functions only available at some workflow keys
+var specialFunctions = []string{"always", "cancelled", "success", "failure", "hashfiles"}
+
+var (
+	statusFunctionNames = []string{"always", "cancelled", "success", "failure"}
+
+	stepContexts = []string{"github", "needs", "strategy", "matrix", "job", "runner", "env", "vars", "secrets", "steps", "inputs"}
+
+	compositeStepContexts = []string{"github", "inputs", "strategy", "matrix", "steps", "job", "runner", "env"}
+)
+
+// availability is GitHub's table of context and function availability, see
+// https://docs.github.com/en/actions/learn-github-actions/contexts#context-availability
+var availability = map[string]*Availability{
+	"run-name":    {Contexts: []string{"github", "inputs", "vars"}},
+	"concurrency": {Contexts: []string{"github", "inputs", "vars"}},
+	"env":         {Contexts: []string{"github", "secrets", "inputs", "vars"}},
+
+	"jobs.<job_id>.concurrency":                        {Contexts: []string{"github", "needs", "strategy", "matrix", "inputs", "vars"}},
+	"jobs.<job_id>.container":                          {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "inputs"}},
+	"jobs.<job_id>.container.credentials":              {Contexts: []string{"github", "needs", "strategy", "matrix", "env", "vars", "secrets", "inputs"}},
+	"jobs.<job_id>.container.env.<env_id>":             {Contexts: []string{"github", "needs", "strategy", "matrix", "job", "runner", "env", "vars", "secrets", "inputs"}},
+	"jobs.<job_id>.container.image":                    {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "inputs"}},
+	"jobs.<job_id>.continue-on-error":                  {Contexts: []string{"github", "needs", "strategy", "vars", "matrix", "inputs"}},
+	"jobs.<job_id>.defaults.run":                       {Contexts: []string{"github", "needs", "strategy", "matrix", "env", "vars", "inputs"}},
+	"jobs.<job_id>.env":                                {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "secrets", "inputs"}},
+	"jobs.<job_id>.environment":                        {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "inputs"}},
+	"jobs.<job_id>.environment.url":                    {Contexts: []string{"github", "needs", "strategy", "matrix", "job", "runner", "env", "vars", "steps", "inputs"}},
+	"jobs.<job_id>.if":                                 {Contexts: []string{"github", "needs", "vars", "inputs"}, Functions: statusFunctionNames},
+	"jobs.<job_id>.name":                               {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "inputs"}},
+	"jobs.<job_id>.outputs.<output_id>":                {Contexts: []string{"github", "needs", "strategy", "matrix", "job", "runner", "env", "vars", "secrets", "steps", "inputs"}},
+	"jobs.<job_id>.runs-on":                            {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "inputs"}},
+	"jobs.<job_id>.secrets.<secrets_id>":               {Contexts: []string{"github", "needs", "strategy", "matrix", "secrets", "inputs", "vars"}},
+	"jobs.<job_id>.services":                           {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "inputs"}},
+	"jobs.<job_id>.services.<service_id>.credentials":  {Contexts: []string{"github", "needs", "strategy", "matrix", "env", "vars", "secrets", "inputs"}},
+	"jobs.<job_id>.services.<service_id>.env.<env_id>": {Contexts: []string{"github", "needs", "strategy", "matrix", "job", "runner", "env", "vars", "secrets", "inputs"}},
+	"jobs.<job_id>.steps.continue-on-error":            {Contexts: stepContexts, Functions: []string{"hashfiles"}},
+	"jobs.<job_id>.steps.env":                          {Contexts: stepContexts, Functions: []string{"hashfiles"}},
+	"jobs.<job_id>.steps.if":                           {Contexts: []string{"github", "needs", "strategy", "matrix", "job", "runner", "env", "vars", "steps", "inputs"}, Functions: append([]string{"hashfiles"}, statusFunctionNames...)},
+	"jobs.<job_id>.steps.name":                         {Contexts: stepContexts, Functions: []string{"hashfiles"}},
+	"jobs.<job_id>.steps.run":                          {Contexts: stepContexts, Functions: []string{"hashfiles"}},
+	"jobs.<job_id>.steps.timeout-minutes":              {Contexts: stepContexts, Functions: []string{"hashfiles"}},
+	"jobs.<job_id>.steps.with":                         {Contexts: stepContexts, Functions: []string{"hashfiles"}},
+	"jobs.<job_id>.steps.working-directory":            {Contexts: stepContexts, Functions: []string{"hashfiles"}},
+	"jobs.<job_id>.strategy":                           {Contexts: []string{"github", "needs", "vars", "inputs"}},
+	"jobs.<job_id>.timeout-minutes":                    {Contexts: []string{"github", "needs", "strategy", "matrix", "vars", "inputs"}},
+	"jobs.<job_id>.with.<with_id>":                     {Contexts: []string{"github", "needs", "strategy", "matrix", "inputs", "vars"}},
+	"on.workflow_call.inputs.<inputs_id>.default":      {Contexts: []string{"github", "inputs", "vars"}},
+	"on.workflow_call.outputs.<output_id>.value":       {Contexts: []string{"github", "jobs", "vars", "inputs"}},
+
+	// Keys of action metadata files, from the schema of the runner
+	"inputs.<inputs_id>.default":   {Contexts: []string{"github", "strategy", "matrix", "job", "runner"}, Functions: []string{"hashfiles"}},
+	"outputs.<output_id>.value":    {Contexts: compositeStepContexts},
+	"runs.steps.continue-on-error": {Contexts: compositeStepContexts, Functions: []string{"hashfiles"}},
+	"runs.steps.env":               {Contexts: compositeStepContexts, Functions: []string{"hashfiles"}},
+	"runs.steps.if":                {Contexts: compositeStepContexts, Functions: append([]string{"hashfiles"}, statusFunctionNames...)},
+	"runs.steps.name":              {Contexts: compositeStepContexts, Functions: []string{"hashfiles"}},
+	"runs.steps.run":               {Contexts: compositeStepContexts, Functions: []string{"hashfiles"}},
+	"runs.steps.with":              {Contexts: compositeStepContexts, Functions: []string{"hashfiles"}},
+	"runs.steps.working-directory": {Contexts: compositeStepContexts, Functions: []string{"hashfiles"}},
+}
+
+// LookupAvailability returns the contexts and functions available at a workflow key. Keys are
+// given like in GitHub's table, e.g. `jobs.<job_id>.steps.if`, or as a concrete path like
+// `jobs.build.steps.2.if`. Ok is false for keys that don't allow expressions or are unknown.
+func LookupAvailability(key string) (a *Availability, ok bool) {
+	// Step indexes are not part of the keys in the table
+	segments := []string{}
+	for i, s := range strings.Split(key, ".") {
+		if _, err := strconv.Atoi(s); err == nil && i > 0 && segments[len(segments)-1] == "steps" {
+			continue
+		}
+		segments = append(segments, s)
+	}
+
+	for k, a := range availability {
+		if matchKey(strings.Split(k, "."), segments) {
+			return a, true
+		}
+	}
+
+	return nil, false
+}
+
+// matchKey returns true if the segments of a key match the segments of a key in the table, where
+// placeholders like `<job_id>` match any segment
+func matchKey(pattern []string, segments []string) bool {
+	if len(pattern) != len(segments) {
+		return false
+	}
+
+	for i, p := range pattern {
+		if strings.HasPrefix(p, "<") && strings.HasSuffix(p, ">") {
+			continue
+		}
+
+		if p != segments[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ContextAvailable returns true if the context is available
+func (a *Availability) ContextAvailable(name string) bool {
+	return containsName(a.Contexts, name)
+}
+
+// FunctionAvailable returns true if the function is available
+func (a *Availability) FunctionAvailable(name string) bool {
+	return !containsName(specialFunctions, name) || containsName(a.Functions, name)
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckAvailability returns an error for every context and function used by the expression that
+// is not available at the workflow key. An error is returned for unknown keys.
+func CheckAvailability(n actionlint.ExprNode, key string) ([]*actionlint.ExprError, error) {
+	a, ok := LookupAvailability(key)
+	if !ok {
+		return nil, fmt.Errorf("unknown workflow key %q", key)
+	}
+
+	errs := []*actionlint.ExprError{}
+	actionlint.VisitExprNode(n, func(node, _ actionlint.ExprNode, entering bool) {
+		if !entering {
+			return
+		}
+
+		var err error
+		switch tn := node.(type) {
+		case *actionlint.VariableNode:
+			err = a.checkContext(tn.Name, key)
+		case *actionlint.FuncCallNode:
+			err = a.checkFunction(tn.Callee, key)
+		}
+
+		if err != nil {
+			errs = append(errs, &actionlint.ExprError{
+				Message: err.Error(),
+				Offset:  node.Token().Offset,
+				Line:    node.Token().Line,
+				Column:  node.Token().Column,
+			})
+		}
+	})
+
+	return errs, nil
+}
+
+func (a *Availability) checkContext(name string, key string) error {
+	if !a.ContextAvailable(name) {
+		return fmt.Errorf("context %q is not available in %s. Available contexts: %s", name, key, strings.Join(a.Contexts, ", "))
+	}
+
+	return nil
+}
+
+func (a *Availability) checkFunction(name string, key string) error {
+	if !a.FunctionAvailable(name) {
+		return fmt.Errorf("function %q is not available in %s", name, key)
+	}
+
+	return nil
+}
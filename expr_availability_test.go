@@ -0,0 +1,99 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/rhysd/actionlint"
+)
+
+func TestLookupAvailability(t *testing.T) {
+	tests := []struct {
+		key      string
+		wantOk   bool
+		context  string
+		function string
+		want     bool
+	}{
+		{"jobs.<job_id>.if", true, "needs", "success", true},
+		{"jobs.build.if", true, "env", "", false},
+		{"jobs.build.if", true, "", "hashFiles", false},
+		{"jobs.build.steps.if", true, "steps", "hashFiles", true},
+		{"jobs.build.steps.3.if", true, "secrets", "", false},
+		{"jobs.build.steps.3.with", true, "secrets", "hashfiles", true},
+		{"concurrency", true, "env", "", false},
+		{"jobs.build.outputs.version", true, "steps", "format", true},
+		{"on.workflow_call.outputs.result.value", true, "jobs", "", true},
+		{"jobs.build.runs-on.label", false, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			a, ok := LookupAvailability(tt.key)
+			if ok != tt.wantOk {
+				t.Fatalf("LookupAvailability() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+
+			got := true
+			if tt.context != "" {
+				got = got && a.ContextAvailable(tt.context)
+			}
+			if tt.function != "" {
+				got = got && a.FunctionAvailable(tt.function)
+			}
+			if got != tt.want {
+				t.Errorf("LookupAvailability() available = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckAvailability(t *testing.T) {
+	n := parseExpr(t, "env.DEPLOY == 'true' && success() && hashFiles('**/go.sum') != '' && github.ref == 'refs/heads/main'")
+
+	errs, err := CheckAvailability(n, "jobs.build.if")
+	if err != nil {
+		t.Fatalf("CheckAvailability() error = %v", err)
+	}
+
+	if len(errs) != 2 || errs[0].Column != 1 || errs[1].Column != 38 {
+		t.Errorf("CheckAvailability() = %v, want errors for env and hashFiles", errs)
+	}
+
+	if _, err := CheckAvailability(n, "jobs.build.unknown"); err == nil {
+		t.Errorf("CheckAvailability() error = nil, want error for unknown key")
+	}
+}
+
+func TestEvaluateWithOptions_Location(t *testing.T) {
+	context := ContextData{"github": ContextData{"ref": "refs/heads/main"}, "env": ContextData{"deploy": "true"}}
+
+	r, err := EvaluateWithOptions(parseExpr(t, "github.ref == 'refs/heads/main'"), context, EvaluateOptions{Location: "jobs.build.if"})
+	if err != nil || r.Value != true {
+		t.Errorf("EvaluateWithOptions() = %v, %v, want true", r, err)
+	}
+
+	if _, err := EvaluateWithOptions(parseExpr(t, "env.deploy == 'true'"), context, EvaluateOptions{Location: "jobs.build.if"}); err == nil {
+		t.Errorf("EvaluateWithOptions() error = nil, want error for unavailable context")
+	}
+
+	if _, err := EvaluateWithOptions(parseExpr(t, "always()"), context, EvaluateOptions{Location: "jobs.build.steps.run"}); err == nil {
+		t.Errorf("EvaluateWithOptions() error = nil, want error for unavailable function")
+	}
+
+	if _, err := EvaluateWithOptions(parseExpr(t, "env.deploy == 'true'"), context, EvaluateOptions{Location: "jobs.build.steps.0.env"}); err != nil {
+		t.Errorf("EvaluateWithOptions() error = %v", err)
+	}
+}
+
+func parseExpr(t *testing.T, src string) actionlint.ExprNode {
+	t.Helper()
+
+	n, err := actionlint.NewExprParser().Parse(actionlint.NewExprLexer(src + "}}"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return n
+}
@@ -0,0 +1,128 @@
+package expr
+
+import (
+	"fmt"
+
+	"github.com/rhysd/actionlint"
+)
+
+// TypeDiagnostic is a problem found while statically checking an expression.
+type TypeDiagnostic struct {
+	*actionlint.ExprError
+
+	// Warning is set for diagnostics that don't prevent evaluation, like comparisons between
+	// values of incompatible types which always evaluate to false.
+	Warning bool
+}
+
+// TypeChecker infers the type of an expression before it is evaluated. It reuses actionlint's
+// semantics checker and its built-in context types, so the types of contexts like `matrix`,
+// `steps`, or `needs` can be refined via the embedded Update* methods.
+type TypeChecker struct {
+	*actionlint.ExprSemanticsChecker
+}
+
+func NewTypeChecker() *TypeChecker {
+	return &TypeChecker{actionlint.NewExprSemanticsChecker(false)}
+}
+
+// Infer returns the static type of the given expression. Errors reported by actionlint are
+// returned as non-warning diagnostics, comparisons between incompatible types as warnings.
+func (c *TypeChecker) Infer(n actionlint.ExprNode) (actionlint.ExprType, []*TypeDiagnostic) {
+	ty, errs := c.Check(n)
+
+	diags := make([]*TypeDiagnostic, 0, len(errs))
+	for _, err := range errs {
+		diags = append(diags, &TypeDiagnostic{ExprError: err})
+	}
+
+	// Collect comparisons first, checking sub-expressions resets the state of the checker
+	compares := []*actionlint.CompareOpNode{}
+	actionlint.VisitExprNode(n, func(node, _ actionlint.ExprNode, entering bool) {
+		if cn, ok := node.(*actionlint.CompareOpNode); ok && entering {
+			compares = append(compares, cn)
+		}
+	})
+
+	for _, cn := range compares {
+		lt, _ := c.Check(cn.Left)
+		rt, _ := c.Check(cn.Right)
+
+		if !comparableTypes(lt, rt) {
+			diags = append(diags, &TypeDiagnostic{
+				ExprError: &actionlint.ExprError{
+					Message: fmt.Sprintf("comparing values of incompatible types %q and %q", lt.String(), rt.String()),
+					Offset:  cn.Token().Offset,
+					Line:    cn.Token().Line,
+					Column:  cn.Token().Column,
+				},
+				Warning: true,
+			})
+		}
+	}
+
+	return ty, diags
+}
+
+// InferCondition is like Infer but for expressions used in `if:` conditions. Conditions whose type
+// is an object or an array are always truthy, so they are reported as errors.
+func (c *TypeChecker) InferCondition(n actionlint.ExprNode) (actionlint.ExprType, []*TypeDiagnostic) {
+	ty, diags := c.Infer(n)
+
+	switch ty.(type) {
+	case *actionlint.ObjectType, *actionlint.ArrayType:
+		diags = append(diags, &TypeDiagnostic{
+			ExprError: &actionlint.ExprError{
+				Message: fmt.Sprintf("condition is of type %q and will always be true", ty.String()),
+				Offset:  n.Token().Offset,
+				Line:    n.Token().Line,
+				Column:  n.Token().Column,
+			},
+		})
+	}
+
+	return ty, diags
+}
+
+// comparableTypes returns false when comparing values of the given types will always coerce at
+// least one side to NaN or compare by reference, which is almost always a mistake.
+func comparableTypes(l, r actionlint.ExprType) bool {
+	lk, rk := staticKind(l), staticKind(r)
+
+	// Unknown types or null can be compared with anything
+	if lk == "" || rk == "" || lk == "null" || rk == "null" {
+		return true
+	}
+
+	if lk == rk {
+		return true
+	}
+
+	// Numeric strings are coerced into numbers
+	if (lk == "number" && rk == "string") || (lk == "string" && rk == "number") {
+		return true
+	}
+
+	return false
+}
+
+// staticKind returns the kind of the type as returned by actionlint's checker. Any type is returned
+// as empty string.
+func staticKind(ty actionlint.ExprType) string {
+	switch ty.(type) {
+	case actionlint.NullType, *actionlint.NullType:
+		return "null"
+	case actionlint.BoolType, *actionlint.BoolType:
+		return "bool"
+	case actionlint.NumberType, *actionlint.NumberType:
+		return "number"
+	case actionlint.StringType, *actionlint.StringType:
+		return "string"
+	case *actionlint.ObjectType:
+		return "object"
+	case *actionlint.ArrayType:
+		return "array"
+	default:
+		return ""
+	}
+}
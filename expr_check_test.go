@@ -0,0 +1,99 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/rhysd/actionlint"
+)
+
+func TestTypeChecker_Infer(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		want     string
+		errors   int
+		warnings int
+	}{
+		{"literal", "'test'", "string", 0, 0},
+		{"context property", "github.event_name", "string", 0, 0},
+		{"context object", "github.event", "object", 0, 0},
+		{"function result", "startsWith(github.ref, 'refs/tags/')", "bool", 0, 0},
+		{"undefined context", "foo.bar", "any", 1, 0},
+		{"comparison number string", "github.run_number == '1'", "bool", 0, 0},
+		{"comparison any string", "github.event.pull_request.merged == 'true'", "bool", 0, 0},
+		{"comparison bool string", "startsWith(github.ref, 'refs/tags/') == 'true'", "bool", 0, 1},
+		{"comparison string bool", "github.ref_name == true", "bool", 0, 1},
+		{"comparison object string", "github.event == 'push'", "bool", 0, 1},
+		{"comparison null", "github.ref_name != null", "bool", 0, 0},
+		{"nested comparison", "(github.ref_name == true) && (runner.os == 'Linux')", "bool", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexer := actionlint.NewExprLexer(tt.input + "}}")
+			parser := actionlint.NewExprParser()
+			n, perr := parser.Parse(lexer)
+			if perr != nil {
+				t.Fatal(perr.Error())
+			}
+
+			got, diags := NewTypeChecker().Infer(n)
+			if got.String() != tt.want {
+				t.Errorf("Infer() type = %v, want %v", got.String(), tt.want)
+			}
+
+			errors, warnings := 0, 0
+			for _, d := range diags {
+				if d.Warning {
+					warnings++
+				} else {
+					errors++
+				}
+			}
+			if errors != tt.errors || warnings != tt.warnings {
+				t.Errorf("Infer() errors = %d, warnings = %d, want %d, %d (%v)", errors, warnings, tt.errors, tt.warnings, diags)
+			}
+		})
+	}
+}
+
+func TestTypeChecker_InferCondition(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"github.event_name == 'push'", false},
+		{"github.event", true},
+		{"github.event.pull_request", false},
+		{"github.event.commits", false},
+		{"steps.build.outputs", true},
+		{"success() && github.ref == 'refs/heads/main'", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			lexer := actionlint.NewExprLexer(tt.input + "}}")
+			parser := actionlint.NewExprParser()
+			n, perr := parser.Parse(lexer)
+			if perr != nil {
+				t.Fatal(perr.Error())
+			}
+
+			c := NewTypeChecker()
+			c.UpdateSteps(actionlint.NewStrictObjectType(map[string]actionlint.ExprType{
+				"build": actionlint.NewStrictObjectType(map[string]actionlint.ExprType{
+					"outputs": actionlint.NewMapObjectType(actionlint.StringType{}),
+				}),
+			}))
+
+			_, diags := c.InferCondition(n)
+			gotErr := false
+			for _, d := range diags {
+				if !d.Warning {
+					gotErr = true
+				}
+			}
+			if gotErr != tt.wantErr {
+				t.Errorf("InferCondition() error = %v, want %v (%v)", gotErr, tt.wantErr, diags)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package expr
+
+import (
+	"sort"
+
+	"github.com/rhysd/actionlint"
+)
+
+// filter evaluates a wildcard access like `inputs.*`. The result is an array of the values of an
+// object, or the elements of an array. Filtering a filtered array flattens it.
+func filter(receiver *EvaluationResult) *EvaluationResult {
+	values := []interface{}{}
+
+	switch v := receiver.Value.(type) {
+	case ContextData:
+		values = append(values, objectValues(v)...)
+
+	case []interface{}:
+		if at, ok := receiver.Type.(*actionlint.ArrayType); ok && at.Deref {
+			for _, e := range v {
+				switch ev := e.(type) {
+				case ContextData:
+					values = append(values, objectValues(ev)...)
+				case []interface{}:
+					values = append(values, ev...)
+				}
+			}
+		} else {
+			values = append(values, v...)
+		}
+	}
+
+	return filtered(values, receiver.Sensitive)
+}
+
+// filterProperty evaluates a property access on a filtered array like `inputs.*.foo`. The result
+// contains the property of every object in the array that has it.
+func filterProperty(receiver *EvaluationResult, property string) *EvaluationResult {
+	values := []interface{}{}
+
+	for _, e := range receiver.Value.([]interface{}) {
+		if obj, ok := e.(ContextData); ok {
+			if _, v, ok := LookupProperty(obj, property); ok {
+				values = append(values, v)
+			}
+		}
+	}
+
+	return filtered(values, receiver.Sensitive)
+}
+
+func filtered(values []interface{}, sensitive bool) *EvaluationResult {
+	return &EvaluationResult{
+		Value:     values,
+		Type:      &actionlint.ArrayType{Elem: getElemType(values), Deref: true},
+		Sensitive: sensitive,
+	}
+}
+
+// objectValues returns the values of the object, ordered by key for a stable result
+func objectValues(obj ContextData) []interface{} {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, obj[k])
+	}
+
+	return values
+}
@@ -1,7 +1,10 @@
 package expr
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/rhysd/actionlint"
@@ -12,93 +15,216 @@ type funcDef struct {
 	// negative values indicate the abs(minimum) number of arguments required
 	argsCount int
 
-	call func(args ...*EvaluationResult) *EvaluationResult
+	// derived is set when the result is derived from the values of the arguments, e.g. by
+	// concatenating them. The result is sensitive if any argument is.
+	derived bool
+
+	call func(args ...*EvaluationResult) (*EvaluationResult, error)
 }
 
 var functions map[string]funcDef = map[string]funcDef{
+	"contains": {
+		argsCount: 2,
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
+			search, item := args[0], args[1]
+
+			// Array, check whether any element equals the item
+			if ar, ok := search.Value.([]interface{}); ok {
+				for _, e := range ar {
+					if (&EvaluationResult{Value: e, Type: getExprType(e)}).Equals(item) {
+						return &EvaluationResult{Value: true, Type: &actionlint.BoolType{}}, nil
+					}
+				}
+
+				return &EvaluationResult{Value: false, Type: &actionlint.BoolType{}}, nil
+			}
+
+			if !search.Primitive() || !item.Primitive() {
+				return &EvaluationResult{Value: false, Type: &actionlint.BoolType{}}, nil
+			}
+
+			// Expression string comparisons are string insensitive
+			ss := strings.ToLower(search.CoerceString())
+			is := strings.ToLower(item.CoerceString())
+
+			return &EvaluationResult{Value: strings.Contains(ss, is), Type: &actionlint.BoolType{}}, nil
+		},
+	},
+
 	"startswith": {
 		argsCount: 2,
-		call: func(args ...*EvaluationResult) *EvaluationResult {
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
 			// TODO: Check types of parameters
 			left := args[0]
 			if !left.Primitive() {
-				return &EvaluationResult{false, &actionlint.BoolType{}}
+				return &EvaluationResult{Value: false, Type: &actionlint.BoolType{}}, nil
 			}
 
 			right := args[1]
 			if !left.Primitive() {
-				return &EvaluationResult{false, &actionlint.BoolType{}}
+				return &EvaluationResult{Value: false, Type: &actionlint.BoolType{}}, nil
 			}
 
 			ls := left.CoerceString()
 			rs := right.CoerceString()
 
 			// Expression string comparisons are string insensitive
-			return &EvaluationResult{strings.HasPrefix(strings.ToLower(ls), strings.ToLower(rs)), &actionlint.BoolType{}}
+			return &EvaluationResult{Value: strings.HasPrefix(strings.ToLower(ls), strings.ToLower(rs)), Type: &actionlint.BoolType{}}, nil
 		},
 	},
 
 	"endswith": {
 		argsCount: 2,
-		call: func(args ...*EvaluationResult) *EvaluationResult {
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
 			// TODO: Check types of parameters
 			left := args[0]
 			if !left.Primitive() {
-				return &EvaluationResult{false, &actionlint.BoolType{}}
+				return &EvaluationResult{Value: false, Type: &actionlint.BoolType{}}, nil
 			}
 
 			right := args[1]
 			if !left.Primitive() {
-				return &EvaluationResult{false, &actionlint.BoolType{}}
+				return &EvaluationResult{Value: false, Type: &actionlint.BoolType{}}, nil
 			}
 
 			ls := left.CoerceString()
 			rs := right.CoerceString()
 
 			// Expression string comparisons are string insensitive
-			return &EvaluationResult{strings.HasSuffix(strings.ToLower(ls), strings.ToLower(rs)), &actionlint.BoolType{}}
+			return &EvaluationResult{Value: strings.HasSuffix(strings.ToLower(ls), strings.ToLower(rs)), Type: &actionlint.BoolType{}}, nil
+		},
+	},
+
+	"format": {
+		argsCount: -1,
+		derived:   true,
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
+			v, err := formatString(args[0].CoerceString(), args[1:])
+			if err != nil {
+				return nil, err
+			}
+
+			return &EvaluationResult{Value: v, Type: &actionlint.StringType{}}, nil
 		},
 	},
 
 	"join": {
 		argsCount: -1,
-		call: func(args ...*EvaluationResult) *EvaluationResult {
+		derived:   true,
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
 			separator := ","
 
 			// String
 			if args[0].Primitive() {
-				return args[0]
+				return args[0], nil
 			}
 
 			if len(args) > 1 {
 				separator = args[1].CoerceString()
 			}
 
-			ar := args[0].Value.([]interface{})
+			ar, ok := args[0].Value.([]interface{})
+			if !ok {
+				return &EvaluationResult{Value: "", Type: &actionlint.StringType{}}, nil
+			}
 
 			v := make([]string, len(ar))
 			for i, a := range ar {
-				ar := &EvaluationResult{a, getExprType(a)}
+				ar := &EvaluationResult{Value: a, Type: getExprType(a)}
 				v[i] = ar.CoerceString()
 			}
 
-			return &EvaluationResult{strings.Join(v, separator), &actionlint.StringType{}}
+			return &EvaluationResult{Value: strings.Join(v, separator), Type: &actionlint.StringType{}}, nil
+		},
+	},
+
+	"tojson": {
+		argsCount: 1,
+		derived:   true,
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
+			// Unlike json.Marshal, GitHub doesn't escape characters like `<` and `&`
+			var b bytes.Buffer
+			enc := json.NewEncoder(&b)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(args[0].Value); err != nil {
+				// Values that cannot be represented, like NaN
+				return &EvaluationResult{Value: "null", Type: &actionlint.StringType{}}, nil
+			}
+
+			return &EvaluationResult{Value: strings.TrimSuffix(b.String(), "\n"), Type: &actionlint.StringType{}}, nil
 		},
 	},
 
 	"fromjson": {
 		argsCount: 1,
-		call: func(args ...*EvaluationResult) *EvaluationResult {
+		derived:   true,
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
 			input := args[0]
 			inputStr := input.CoerceString()
 
-			var v ContextData
+			var v interface{}
 			if err := json.Unmarshal([]byte(inputStr), &v); err != nil {
 				// Ignore
 				v = ContextData{}
 			}
 
-			return &EvaluationResult{v, &actionlint.ObjectType{}}
+			return &EvaluationResult{Value: v, Type: getExprType(v)}, nil
 		},
 	},
+
+	// hashFiles hashes files in the workspace of the runner, so its result is only known at runtime
+	"hashfiles": {
+		argsCount: -1,
+		call: func(args ...*EvaluationResult) (*EvaluationResult, error) {
+			return &EvaluationResult{Value: Unknown, Type: &actionlint.AnyType{}}, nil
+		},
+	},
+}
+
+// formatString replaces the `{N}` placeholders in the format string with the given arguments. `{{`
+// and `}}` are used to escape braces. Like on GitHub, other braces and placeholders without an
+// argument are an error.
+func formatString(format string, args []*EvaluationResult) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+
+		switch {
+		case c == '{' && strings.HasPrefix(format[i:], "{{"):
+			b.WriteByte('{')
+			i++
+
+		case c == '}' && strings.HasPrefix(format[i:], "}}"):
+			b.WriteByte('}')
+			i++
+
+		case c == '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("invalid format string %q: unclosed placeholder", format)
+			}
+
+			n := format[i+1 : i+end]
+			idx, err := strconv.Atoi(n)
+			if err != nil || strings.Trim(n, "0123456789") != "" {
+				return "", fmt.Errorf("invalid format string %q: invalid placeholder %q", format, format[i:i+end+1])
+			}
+			if idx >= len(args) {
+				return "", fmt.Errorf("invalid format string %q: no argument for placeholder %q", format, format[i:i+end+1])
+			}
+
+			b.WriteString(args[idx].CoerceString())
+			i += end
+
+		case c == '}':
+			return "", fmt.Errorf("invalid format string %q: unescaped '}'", format)
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), nil
 }
@@ -0,0 +1,333 @@
+package expr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rhysd/actionlint"
+)
+
+// DefaultUntrustedInputs are context paths whose values can be controlled by an attacker. `*`
+// matches any property or array element. It contains actionlint's built-in untrusted inputs and
+// the fields of `workflow_run` events that are copied from the triggering workflow.
+var DefaultUntrustedInputs = append(flattenUntrustedInputs(actionlint.BuiltinUntrustedInputs),
+	"github.event.workflow_run.head_branch",
+	"github.event.workflow_run.head_commit.message",
+	"github.event.workflow_run.head_commit.author.email",
+	"github.event.workflow_run.head_commit.author.name",
+)
+
+func flattenUntrustedInputs(roots actionlint.UntrustedInputSearchRoots) []string {
+	paths := []string{}
+
+	var walk func(m *actionlint.UntrustedInputMap)
+	walk = func(m *actionlint.UntrustedInputMap) {
+		if len(m.Children) == 0 {
+			paths = append(paths, m.String())
+			return
+		}
+
+		for _, c := range m.Children {
+			walk(c)
+		}
+	}
+
+	for _, r := range roots {
+		walk(r)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// UntrustedSources returns the untrusted inputs matching the given patterns whose values flow into
+// the result of the expression. Values are followed through object filters, functions deriving
+// their result from their arguments like `format` or `join`, and the logical operators. Objects
+// containing untrusted inputs, like `github.event`, are returned once by their own path.
+func UntrustedSources(n actionlint.ExprNode, patterns []string) []string {
+	path, sources := untrustedFlow(n, patterns)
+	sources = append(sources, matchUntrusted(path, patterns)...)
+
+	return uniqueSorted(sources)
+}
+
+// untrustedFlow returns the context path of the node, if it's a context access, and the untrusted
+// sources flowing into it otherwise.
+func untrustedFlow(n actionlint.ExprNode, patterns []string) ([]string, []string) {
+	switch tn := n.(type) {
+	case *actionlint.VariableNode:
+		return []string{tn.Name}, nil
+
+	case *actionlint.ObjectDerefNode:
+		path, sources := untrustedFlow(tn.Receiver, patterns)
+		if path == nil {
+			return nil, sources
+		}
+		return append(path, tn.Property), nil
+
+	case *actionlint.ArrayDerefNode:
+		path, sources := untrustedFlow(tn.Receiver, patterns)
+		if path == nil {
+			return nil, sources
+		}
+		return append(path, "*"), nil
+
+	case *actionlint.IndexAccessNode:
+		path, sources := untrustedFlow(tn.Operand, patterns)
+		if path == nil {
+			return nil, sources
+		}
+
+		switch idx := tn.Index.(type) {
+		case *actionlint.StringNode:
+			return append(path, idx.Value), nil
+		case *actionlint.IntNode:
+			return append(path, strconv.Itoa(idx.Value)), nil
+		default:
+			return append(path, "*"), nil
+		}
+
+	case *actionlint.FuncCallNode:
+		if !functions[strings.ToLower(tn.Callee)].derived {
+			return nil, nil
+		}
+
+		sources := []string{}
+		for _, a := range tn.Args {
+			sources = append(sources, UntrustedSources(a, patterns)...)
+		}
+		return nil, sources
+
+	case *actionlint.LogicalOpNode:
+		return nil, append(UntrustedSources(tn.Left, patterns), UntrustedSources(tn.Right, patterns)...)
+	}
+
+	// Literals, comparisons, and negations don't pass values through
+	return nil, nil
+}
+
+// matchUntrusted returns the patterns matching the path. A path deeper than a pattern matches
+// if the pattern is a prefix of it. A path of an object containing untrusted inputs, like
+// `github.event`, is untrusted, too, and returned once instead of the patterns it contains.
+func matchUntrusted(path []string, patterns []string) []string {
+	if len(path) == 0 {
+		return nil
+	}
+
+	matches := []string{}
+	object := false
+	for _, p := range patterns {
+		ps := strings.Split(p, ".")
+		if !prefixMatch(path, ps) {
+			continue
+		}
+
+		if len(path) < len(ps) {
+			object = true
+		} else {
+			matches = append(matches, p)
+		}
+	}
+
+	if len(matches) == 0 && object {
+		return []string{strings.Join(path, ".")}
+	}
+
+	return matches
+}
+
+// untrustedSubpaths returns the patterns of the untrusted inputs contained in the object at the
+// given path, see matchUntrusted
+func untrustedSubpaths(path string, patterns []string) []string {
+	segments := strings.Split(path, ".")
+
+	var subpaths []string
+	for _, p := range patterns {
+		ps := strings.Split(p, ".")
+		if len(segments) < len(ps) && prefixMatch(segments, ps) {
+			subpaths = append(subpaths, p)
+		}
+	}
+
+	return subpaths
+}
+
+// prefixMatch returns true if the shorter of the path and the pattern is a prefix of the other
+func prefixMatch(path []string, pattern []string) bool {
+	l := len(pattern)
+	if len(path) < l {
+		l = len(path)
+	}
+
+	for i := 0; i < l; i++ {
+		if pattern[i] != "*" && path[i] != "*" && !strings.EqualFold(pattern[i], path[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func uniqueSorted(s []string) []string {
+	seen := map[string]bool{}
+	r := []string{}
+	for _, v := range s {
+		if !seen[v] {
+			seen[v] = true
+			r = append(r, v)
+		}
+	}
+
+	sort.Strings(r)
+
+	return r
+}
+
+// InjectionFinding is an untrusted input interpolated into a script
+type InjectionFinding struct {
+	// Source is the untrusted input flowing into the script
+	Source string
+
+	// Untrusted are the untrusted inputs contained in Source if it's an object, like
+	// `github.event.issue.title` for `github.event`
+	Untrusted []string
+
+	// Expression is the source of the interpolated `${{ }}` expression
+	Expression string
+
+	Job  string
+	Step int
+
+	// Sink is `run` for shell scripts, or `github-script` for scripts of actions/github-script
+	Sink string
+
+	// Pos is the position of the script in the workflow, Offset the offset of the expression in
+	// the script
+	Pos    *actionlint.Pos
+	Offset int
+
+	// EnvName is the name of the environment variable suggested to pass the value to the script
+	EnvName    string
+	Suggestion string
+}
+
+func (f *InjectionFinding) String() string {
+	if len(f.Untrusted) > 0 {
+		return fmt.Sprintf("%d:%d: %q contains potentially untrusted inputs like %s and flows into the %s script of step %d of job %q. %s", f.Pos.Line, f.Pos.Col, f.Source, strings.Join(f.Untrusted, ", "), f.Sink, f.Step, f.Job, f.Suggestion)
+	}
+
+	return fmt.Sprintf("%d:%d: %q is potentially untrusted and flows into the %s script of step %d of job %q. %s", f.Pos.Line, f.Pos.Col, f.Source, f.Sink, f.Step, f.Job, f.Suggestion)
+}
+
+// FindScriptInjections reports untrusted inputs matching the given patterns that are interpolated
+// into `run:` scripts or the `script:` input of actions/github-script. If patterns is nil,
+// DefaultUntrustedInputs are used.
+func FindScriptInjections(w *actionlint.Workflow, patterns []string) ([]*InjectionFinding, error) {
+	if patterns == nil {
+		patterns = DefaultUntrustedInputs
+	}
+
+	ids := make([]string, 0, len(w.Jobs))
+	for id := range w.Jobs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	findings := []*InjectionFinding{}
+
+	for _, id := range ids {
+		for idx, step := range w.Jobs[id].Steps {
+			var script *actionlint.String
+			var sink string
+
+			switch e := step.Exec.(type) {
+			case *actionlint.ExecRun:
+				script, sink = e.Run, "run"
+
+			case *actionlint.ExecAction:
+				if e.Uses != nil && strings.HasPrefix(e.Uses.Value, "actions/github-script@") {
+					if in, ok := e.Inputs["script"]; ok {
+						script, sink = in.Value, "github-script"
+					}
+				}
+			}
+
+			if script == nil {
+				continue
+			}
+
+			exprs, err := ParseTemplate(script.Value)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse script of step %d of job %q: %w", idx, id, err)
+			}
+
+			for _, e := range exprs {
+				for _, src := range UntrustedSources(e.Node, patterns) {
+					env := envNameForPath(src)
+
+					f := &InjectionFinding{
+						Source:     src,
+						Untrusted:  untrustedSubpaths(src, patterns),
+						Expression: e.Source,
+						Job:        id,
+						Step:       idx,
+						Sink:       sink,
+						Pos:        script.Pos,
+						Offset:     e.Offset,
+						EnvName:    env,
+					}
+
+					ref := `"$` + env + `"`
+					if sink == "github-script" {
+						ref = "process.env." + env
+					}
+					f.Suggestion = fmt.Sprintf("pass it through an environment variable with `env: { %s: ${{ %s }} }` and use %s in the script instead", env, e.Source, ref)
+
+					findings = append(findings, f)
+				}
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// envNameForPath derives an environment variable name from the last segments of the path, like
+// ISSUE_TITLE for `github.event.issue.title`. Characters other than letters, digits, and `_`, like
+// in `github.event['head-commit']`, are replaced with `_`.
+func envNameForPath(path string) string {
+	segments := strings.Split(path, ".")
+
+	parts := []string{}
+	for _, p := range segments {
+		if p == "*" || strings.EqualFold(p, "github") || strings.EqualFold(p, "event") {
+			continue
+		}
+		parts = append(parts, p)
+	}
+
+	// Objects like `github.event` are named after their last segment
+	if len(parts) == 0 {
+		parts = segments[len(segments)-1:]
+	}
+
+	if len(parts) > 2 {
+		parts = parts[len(parts)-2:]
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(strings.Join(parts, "_")))
+
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+
+	return name
+}
@@ -0,0 +1,120 @@
+package expr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rhysd/actionlint"
+)
+
+func TestUntrustedSources(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"github.event.issue.title", []string{"github.event.issue.title"}},
+		{"github.event.issue['title']", []string{"github.event.issue.title"}},
+		{"github.head_ref", []string{"github.head_ref"}},
+		{"github.event.commits[0].message", []string{"github.event.commits.*.message"}},
+		{"github.event.commits.*.message", []string{"github.event.commits.*.message"}},
+		{"join(github.event.commits.*.author.name, ', ')", []string{"github.event.commits.*.author.name"}},
+		{"format('{0}: {1}', github.event_name, github.event.comment.body)", []string{"github.event.comment.body"}},
+		{"github.event.pull_request.head.ref || github.ref", []string{"github.event.pull_request.head.ref"}},
+		{"toJSON(github.event.issue)", []string{"github.event.issue"}},
+		{"github.event", []string{"github.event"}},
+		{"github", []string{"github"}},
+		{"fromJSON(github.event.comment.body).command", []string{"github.event.comment.body"}},
+		{"github.event.issue.title == 'test'", []string{}},
+		{"startsWith(github.head_ref, 'feature/')", []string{}},
+		{"github.event.issue.number", []string{}},
+		{"github.event_name", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			lexer := actionlint.NewExprLexer(tt.input + "}}")
+			parser := actionlint.NewExprParser()
+			n, perr := parser.Parse(lexer)
+			if perr != nil {
+				t.Fatal(perr.Error())
+			}
+
+			if got := UntrustedSources(n, DefaultUntrustedInputs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UntrustedSources() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindScriptInjections(t *testing.T) {
+	src := `on: issues
+jobs:
+  triage:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ github.event.issue.title }}"
+      - run: echo "${{ github.event.issue.number }}"
+      - uses: actions/github-script@v6
+        with:
+          script: |
+            console.log("${{ format('{0}', github.event.issue.body) }}")
+      - uses: actions/checkout@v3
+        with:
+          ref: ${{ github.head_ref }}
+      - run: echo '${{ toJSON(github.event.issue) }}'
+`
+	w, errs := actionlint.Parse([]byte(src))
+	if len(errs) > 0 {
+		t.Fatal(errs[0].Error())
+	}
+
+	got, err := FindScriptInjections(w, nil)
+	if err != nil {
+		t.Fatalf("FindScriptInjections() error = %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("FindScriptInjections() = %v, want 3 findings", got)
+	}
+
+	if got[0].Source != "github.event.issue.title" || got[0].Sink != "run" || got[0].Step != 0 || got[0].Offset != 6 || got[0].EnvName != "ISSUE_TITLE" {
+		t.Errorf("FindScriptInjections() first finding = %+v", got[0])
+	}
+
+	if got[1].Source != "github.event.issue.body" || got[1].Sink != "github-script" || got[1].Step != 2 || got[1].EnvName != "ISSUE_BODY" {
+		t.Errorf("FindScriptInjections() second finding = %+v", got[1])
+	}
+
+	wantSuggestion := "pass it through an environment variable with `env: { ISSUE_BODY: ${{ format('{0}', github.event.issue.body) }} }` and use process.env.ISSUE_BODY in the script instead"
+	if got[1].Suggestion != wantSuggestion {
+		t.Errorf("FindScriptInjections() suggestion = %v, want %v", got[1].Suggestion, wantSuggestion)
+	}
+
+	// Objects containing untrusted inputs are reported once
+	wantUntrusted := []string{"github.event.issue.body", "github.event.issue.title"}
+	if got[2].Source != "github.event.issue" || got[2].Step != 4 || !reflect.DeepEqual(got[2].Untrusted, wantUntrusted) {
+		t.Errorf("FindScriptInjections() third finding = %+v", got[2])
+	}
+}
+
+func Test_envNameForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"github.event.issue.title", "ISSUE_TITLE"},
+		{"github.event.commits.*.author.name", "AUTHOR_NAME"},
+		{"github.head_ref", "HEAD_REF"},
+		{"github.event.head-commit.message", "HEAD_COMMIT_MESSAGE"},
+		{"github.event.pull_request.labels.0", "LABELS_0"},
+		{"github.event.0day", "_0DAY"},
+		{"github.event", "EVENT"},
+		{"github", "GITHUB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := envNameForPath(tt.path); got != tt.want {
+				t.Errorf("envNameForPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -13,7 +13,117 @@ import (
 
 type ContextData = map[string]interface{}
 
+// Evaluate evaluates the expression with the given contexts. Like on GitHub, `&&` and `||` return
+// the value of the operand that decided the result rather than a boolean.
 func Evaluate(n actionlint.ExprNode, context ContextData) (*EvaluationResult, error) {
+	i := newInterpreter(context)
+	return i.evaluate(n)
+}
+
+// EvaluateOptions configures optional behavior of EvaluateWithOptions
+type EvaluateOptions struct {
+	// Observer is notified about the progress of the evaluation
+	Observer Observer
+
+	// SensitivePaths are context paths whose values are treated like secrets, in addition to the
+	// `secrets` context. For example `github.token`.
+	SensitivePaths []string
+
+	// Status is the status checked by the status check functions like `success()`
+	Status Status
+
+	// Location is the workflow key the expression is used at, like `jobs.<job_id>.if` or
+	// `jobs.build.steps.2.if`, see LookupAvailability. If set, accessing a context or calling a
+	// function that is not available at the key is an error.
+	Location string
+}
+
+func EvaluateWithOptions(n actionlint.ExprNode, context ContextData, opts EvaluateOptions) (*EvaluationResult, error) {
+	i := newInterpreter(context)
+	i.observer = opts.Observer
+	i.sensitivePaths = append(i.sensitivePaths, opts.SensitivePaths...)
+	i.status = opts.Status
+
+	if opts.Location != "" {
+		a, ok := LookupAvailability(opts.Location)
+		if !ok {
+			return nil, fmt.Errorf("unknown workflow key %q", opts.Location)
+		}
+		i.location, i.availability = opts.Location, a
+	}
+
+	return i.evaluate(n)
+}
+
+// interpreter holds the state of a single evaluation
+type interpreter struct {
+	context ContextData
+
+	// sensitivePaths are the context paths whose values are marked as sensitive
+	sensitivePaths []string
+
+	// trace is the trace node of the expression currently being evaluated. Nil unless tracing.
+	trace *TraceNode
+
+	observer Observer
+
+	status Status
+
+	// availability restricts the contexts and functions available at location. Nil if not
+	// restricted.
+	location     string
+	availability *Availability
+
+	// paths maps evaluated context access nodes to their resolved context path
+	paths map[actionlint.ExprNode]string
+
+	// types memoizes the types of the objects of the context
+	types exprTypes
+}
+
+func newInterpreter(context ContextData) *interpreter {
+	return &interpreter{
+		context:        context,
+		sensitivePaths: []string{"secrets"},
+		paths:          map[actionlint.ExprNode]string{},
+		types:          exprTypes{},
+	}
+}
+
+func (i *interpreter) evaluate(n actionlint.ExprNode) (*EvaluationResult, error) {
+	if i.observer != nil {
+		i.observer.EnterNode(n)
+	}
+
+	r, err := i.evaluateTraced(n)
+
+	if i.observer != nil {
+		i.observer.LeaveNode(n, r, err)
+	}
+
+	return r, err
+}
+
+func (i *interpreter) evaluateTraced(n actionlint.ExprNode) (*EvaluationResult, error) {
+	if i.trace == nil {
+		return i.evaluateNode(n)
+	}
+
+	parent := i.trace
+	i.trace = newTraceNode(n)
+	parent.Children = append(parent.Children, i.trace)
+
+	r, err := i.evaluateNode(n)
+	i.trace.setResult(r, err)
+
+	i.trace = parent
+
+	return r, err
+}
+
+func (i *interpreter) evaluateNode(n actionlint.ExprNode) (*EvaluationResult, error) {
+	context := i.context
+
 	switch tn := n.(type) {
 
 	//
@@ -31,29 +141,54 @@ func Evaluate(n actionlint.ExprNode, context ContextData) (*EvaluationResult, er
 	case *actionlint.BoolNode:
 		return &EvaluationResult{Value: tn.Value, Type: &actionlint.BoolType{}}, nil
 
+	case *actionlint.NullNode:
+		return &EvaluationResult{Value: nil, Type: &actionlint.NullType{}}, nil
+
 	//
 	// Context access
 	//
 	case *actionlint.VariableNode:
 		name := tn.Name
+		if i.availability != nil {
+			if err := i.availability.checkContext(name, i.location); err != nil {
+				return nil, err
+			}
+		}
+
 		v, ok := context[name]
 		if !ok {
 			return nil, errors.New("unknown variable access: " + name)
 		}
 
-		vt := getExprType(v)
+		vt := i.types.of(v)
+		r := &EvaluationResult{Value: v, Type: vt}
 
-		return &EvaluationResult{Value: v, Type: vt}, nil
+		i.recordContextAccess(n, nil, name, r)
+
+		return r, nil
 
 	// Access to object via "."
 	case *actionlint.ObjectDerefNode:
-		result, err := Evaluate(tn.Receiver, context)
+		result, err := i.evaluate(tn.Receiver)
 		if err != nil {
 			return nil, errs.Wrap(err, "could not evaluate receiver")
 		}
 
+		if result.IsUnknown() {
+			return unknownResult(result), nil
+		}
+
+		// Property access on the result of a filter, like `inputs.*.test`, returns the properties
+		// of all filtered objects
+		if at, ok := result.Type.(*actionlint.ArrayType); ok && at.Deref {
+			r := filterProperty(result, tn.Property)
+			i.recordContextAccess(n, tn.Receiver, tn.Property, r)
+
+			return r, nil
+		}
+
 		if _, ok := result.Type.(*actionlint.ObjectType); !ok {
-			return &EvaluationResult{nil, &actionlint.NullType{}}, nil
+			return &EvaluationResult{Value: nil, Type: &actionlint.NullType{}}, nil
 		}
 
 		value := result.Value
@@ -62,34 +197,54 @@ func Evaluate(n actionlint.ExprNode, context ContextData) (*EvaluationResult, er
 			return nil, errors.New("invalid result received for receiver")
 		}
 
-		property := tn.Property
-		v, ok := obj[property]
-		if !ok {
-			return nil, errors.New("unknown context access: " + property)
-		}
+		// Missing properties evaluate to null
+		property, v, _ := LookupProperty(obj, tn.Property)
+
+		vt := i.types.of(v)
+		r := &EvaluationResult{Value: v, Type: vt, Sensitive: result.Sensitive}
 
-		vt := getExprType(v)
+		i.recordContextAccess(n, tn.Receiver, property, r)
 
-		return &EvaluationResult{Value: v, Type: vt}, nil
+		return r, nil
 
 	// Access to array of object via []
 	case *actionlint.IndexAccessNode:
-		idxResult, err := Evaluate(tn.Index, context)
+		idxResult, err := i.evaluate(tn.Index)
 		if err != nil {
 			return nil, errs.Wrap(err, "could not evalute index for index access")
 		}
 
-		objResult, err := Evaluate(tn.Operand, context)
+		objResult, err := i.evaluate(tn.Operand)
 		if err != nil {
 			return nil, errs.Wrap(err, "could not get operand for index access")
 		}
 
+		if objResult.IsUnknown() || idxResult.IsUnknown() {
+			return unknownResult(objResult, idxResult), nil
+		}
+
 		if _, ok := objResult.Type.(*actionlint.ArrayType); ok {
-			return arrayAccess(objResult, idxResult)
+			r, err := i.arrayAccess(objResult, idxResult)
+			if err == nil {
+				r.Sensitive = objResult.Sensitive
+			}
+			if idx := convertToNumber(idxResult.Value); err == nil && !math.IsNaN(idx) {
+				i.recordContextAccess(n, tn.Operand, int(idx), r)
+			}
+
+			return r, err
 		}
 
 		if _, ok := objResult.Type.(*actionlint.ObjectType); ok {
-			return objectAccess(objResult, idxResult)
+			r, err := i.objectAccess(objResult, idxResult)
+			if err == nil {
+				r.Sensitive = objResult.Sensitive
+
+				key, _, _ := LookupProperty(objResult.Value.(ContextData), idxResult.CoerceString())
+				i.recordContextAccess(n, tn.Operand, key, r)
+			}
+
+			return r, err
 		}
 
 		// break!
@@ -97,123 +252,136 @@ func Evaluate(n actionlint.ExprNode, context ContextData) (*EvaluationResult, er
 
 	// ArrayDeref is accessing an array with a wild-card, like `inputs.*.test`
 	case *actionlint.ArrayDerefNode:
-		// result, err := Evaluate(tn.Receiver, context)
-		// if err != nil {
-		// 	return nil, errs.Wrap(err, "could not evaluate receiver")
-		// }
+		result, err := i.evaluate(tn.Receiver)
+		if err != nil {
+			return nil, errs.Wrap(err, "could not evaluate receiver")
+		}
+
+		if result.IsUnknown() {
+			return unknownResult(result), nil
+		}
+
+		r := filter(result)
+		i.recordContextAccess(n, tn.Receiver, "*", r)
 
-		// return result, nil
-		panic("wildcard access not implemented")
+		return r, nil
 
 	//
 	// Function call
 	//
 	case *actionlint.FuncCallNode:
+		if i.availability != nil {
+			if err := i.availability.checkFunction(tn.Callee, i.location); err != nil {
+				return nil, err
+			}
+		}
+
 		// Evaluate arguments
 		args := make([]*EvaluationResult, len(tn.Args))
-		for i, arg := range tn.Args {
-			a, err := Evaluate(arg, context)
+		for idx, arg := range tn.Args {
+			a, err := i.evaluate(arg)
 			if err != nil {
 				return nil, err
 			}
 
-			args[i] = a
+			args[idx] = a
 		}
 
-		return fcall(tn.Callee, args)
+		return i.call(tn.Callee, args)
 
 	//
 	// Unary Operators
 	//
 	case *actionlint.NotOpNode:
-		r, err := Evaluate(tn.Operand, context)
+		r, err := i.evaluate(tn.Operand)
 		if err != nil {
 			return nil, err
 		}
 
-		return &EvaluationResult{r.Falsy(), &actionlint.BoolType{}}, nil
+		if !r.TruthinessKnown() {
+			return unknownResult(r), nil
+		}
+
+		i.traceTruthiness(tn.Operand, r)
+
+		return &EvaluationResult{Value: r.Falsy(), Type: &actionlint.BoolType{}}, nil
 
 	//
 	// Binary Operators
 	//
 	case *actionlint.CompareOpNode:
-		left, err := Evaluate(tn.Left, context)
+		left, err := i.evaluate(tn.Left)
 		if err != nil {
 			return nil, err
 		}
-		right, err := Evaluate(tn.Right, context)
+		right, err := i.evaluate(tn.Right)
 		if err != nil {
 			return nil, err
 		}
 
+		if left.IsUnknown() || right.IsUnknown() {
+			return unknownResult(left, right), nil
+		}
+
+		i.traceComparison(tn, left, right)
+
 		switch tn.Kind {
 		case actionlint.CompareOpNodeKindEq:
-			return &EvaluationResult{left.Equals(right), &actionlint.BoolType{}}, nil
+			return &EvaluationResult{Value: left.Equals(right), Type: &actionlint.BoolType{}}, nil
 
 		case actionlint.CompareOpNodeKindNotEq:
-			return &EvaluationResult{!left.Equals(right), &actionlint.BoolType{}}, nil
+			return &EvaluationResult{Value: !left.Equals(right), Type: &actionlint.BoolType{}}, nil
 
 		case actionlint.CompareOpNodeKindGreater:
-			return &EvaluationResult{left.GreaterThan(right), &actionlint.BoolType{}}, nil
+			return &EvaluationResult{Value: left.GreaterThan(right), Type: &actionlint.BoolType{}}, nil
 
 		case actionlint.CompareOpNodeKindGreaterEq:
 			return &EvaluationResult{
-				left.Equals(right) || left.GreaterThan(right),
-				&actionlint.BoolType{},
+				Value: left.Equals(right) || left.GreaterThan(right),
+				Type:  &actionlint.BoolType{},
 			}, nil
 
 		case actionlint.CompareOpNodeKindLess:
-			return &EvaluationResult{left.LessThan(right), &actionlint.BoolType{}}, nil
+			return &EvaluationResult{Value: left.LessThan(right), Type: &actionlint.BoolType{}}, nil
 
 		case actionlint.CompareOpNodeKindLessEq:
 			return &EvaluationResult{
-				left.Equals(right) || left.LessThan(right),
-				&actionlint.BoolType{},
+				Value: left.Equals(right) || left.LessThan(right),
+				Type:  &actionlint.BoolType{},
 			}, nil
 		}
 
 	case *actionlint.LogicalOpNode:
-		_, err := Evaluate(tn.Left, context)
+		left, err := i.evaluate(tn.Left)
 		if err != nil {
 			return nil, err
 		}
-		_, err = Evaluate(tn.Right, context)
-		if err != nil {
-			return nil, err
+
+		if !left.TruthinessKnown() {
+			return i.evaluateUnknownLogicalOp(tn, left)
 		}
 
+		i.traceTruthiness(tn.Left, left)
+
+		// Logical operators return the value of the operand that determined the result, not a
+		// boolean. `a || b` returns a if a is truthy, otherwise b.
 		switch tn.Kind {
 		case actionlint.LogicalOpNodeKindAnd:
-			left, err := Evaluate(tn.Left, context)
-			if err != nil {
-				return nil, err
-			}
-
-			right, err := Evaluate(tn.Right, context)
-			if err != nil {
-				return nil, err
+			if left.Falsy() {
+				// No need to evaluate rhs
+				i.traceShortCircuit(tn.Right)
+				return left, nil
 			}
 
-			return &EvaluationResult{left.Truthy() && right.Truthy(), &actionlint.BoolType{}}, nil
-
 		case actionlint.LogicalOpNodeKindOr:
-			left, err := Evaluate(tn.Left, context)
-			if err != nil {
-				return nil, err
-			}
-
 			if left.Truthy() {
 				// No need to evaluate rhs
-				return &EvaluationResult{true, &actionlint.BoolType{}}, nil
+				i.traceShortCircuit(tn.Right)
+				return left, nil
 			}
-
-			right, err := Evaluate(tn.Right, context)
-			if err != nil {
-				return nil, err
-			}
-
-			return &EvaluationResult{right.Truthy(), &actionlint.BoolType{}}, nil
 		}
+
+		return i.evaluate(tn.Right)
 	}
 
 	panic("unknown node")
@@ -236,10 +404,27 @@ func fcall(name string, args []*EvaluationResult) (*EvaluationResult, error) {
 		}
 	}
 
-	return funcDef.call(args...), nil
+	for _, a := range args {
+		if ContainsUnknown(a.Value) {
+			return unknownResult(args...), nil
+		}
+	}
+
+	r, err := funcDef.call(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if funcDef.derived {
+		for _, a := range args {
+			r.Sensitive = r.Sensitive || a.Sensitive
+		}
+	}
+
+	return r, nil
 }
 
-func arrayAccess(array *EvaluationResult, idx *EvaluationResult) (*EvaluationResult, error) {
+func (i *interpreter) arrayAccess(array *EvaluationResult, idx *EvaluationResult) (*EvaluationResult, error) {
 	// TODO: Assert type of array
 	arrayT := array.Value.([]interface{})
 
@@ -249,24 +434,24 @@ func arrayAccess(array *EvaluationResult, idx *EvaluationResult) (*EvaluationRes
 		idxInt := int(numberIdx)
 
 		if idxInt < 0 || idxInt >= len(arrayT) {
-			return nil, errors.New("index out of range")
+			// Out of range access evaluates to null
+			return &EvaluationResult{Value: nil, Type: &actionlint.NullType{}}, nil
 		}
 
 		v := arrayT[idxInt]
-		return &EvaluationResult{v, getExprType(v)}, nil
+		return &EvaluationResult{Value: v, Type: i.types.of(v)}, nil
 	}
 
-	return &EvaluationResult{nil, &actionlint.AnyType{}}, nil
+	return &EvaluationResult{Value: nil, Type: &actionlint.AnyType{}}, nil
 }
 
-func objectAccess(obj *EvaluationResult, idx *EvaluationResult) (*EvaluationResult, error) {
+func (i *interpreter) objectAccess(obj *EvaluationResult, idx *EvaluationResult) (*EvaluationResult, error) {
 	// Index has to be string
 	if _, ok := idx.Type.(*actionlint.StringType); !ok {
 		return nil, errors.New("index must be string")
 	}
 
-	key := idx.Value.(string)
-	v := obj.Value.(ContextData)[key]
+	_, v, _ := LookupProperty(obj.Value.(ContextData), idx.Value.(string))
 
-	return &EvaluationResult{v, getExprType(v)}, nil
+	return &EvaluationResult{Value: v, Type: i.types.of(v)}, nil
 }
@@ -87,6 +87,12 @@ func Test_Evaluate(t *testing.T) {
 			context: map[string]interface{}{"input": map[string]interface{}{"test2": map[string]interface{}{"test": float64(42)}}},
 			want:    &EvaluationResult{Value: float64(42), Type: &actionlint.NumberType{}},
 		},
+		{
+			name:    "context access - case insensitive object access",
+			input:   "input.test2['TEST']",
+			context: map[string]interface{}{"input": map[string]interface{}{"test2": map[string]interface{}{"Test": float64(42)}}},
+			want:    &EvaluationResult{Value: float64(42), Type: &actionlint.NumberType{}},
+		},
 		{
 			name:    "context access - array access",
 			input:   "input.test[1]",
@@ -212,6 +218,22 @@ func Test_Evaluate(t *testing.T) {
 			input: "(1 == 2) || (1 == 1)",
 			want:  &EvaluationResult{Value: true, Type: &actionlint.BoolType{}},
 		},
+		{
+			name:    "logical or - returns operand",
+			input:   "inputs.name || 'default'",
+			context: map[string]interface{}{"inputs": map[string]interface{}{"name": ""}},
+			want:    &EvaluationResult{Value: "default", Type: &actionlint.StringType{}},
+		},
+		{
+			name:  "logical and - returns operand",
+			input: "'a' && 'b'",
+			want:  &EvaluationResult{Value: "b", Type: &actionlint.StringType{}},
+		},
+		{
+			name:  "logical and - returns falsy operand",
+			input: "0 && 'b'",
+			want:  &EvaluationResult{Value: float64(0), Type: &actionlint.NumberType{}},
+		},
 		{
 			name:  "fcall - startsWith",
 			input: "startsWith('test', 'tE')",
@@ -249,12 +271,32 @@ func Test_Evaluate(t *testing.T) {
 			context: map[string]interface{}{"inputs": map[string]interface{}{"values": []interface{}{"42", "1"}}},
 			want:    &EvaluationResult{Value: "42:1", Type: &actionlint.StringType{}},
 		},
+		{
+			name:    "fcall - format",
+			input:   "format('{0}-{1} {{0}}', inputs.a, 42)",
+			context: map[string]interface{}{"inputs": map[string]interface{}{"a": "x"}},
+			want:    &EvaluationResult{Value: "x-42 {0}", Type: &actionlint.StringType{}},
+		},
+		{
+			name:    "fcall - toJSON",
+			input:   "toJSON(inputs)",
+			context: map[string]interface{}{"inputs": map[string]interface{}{"a": "x"}},
+			want:    &EvaluationResult{Value: "{\n  \"a\": \"x\"\n}", Type: &actionlint.StringType{}},
+		},
+		{
+			name:    "fcall - toJSON - html characters",
+			input:   "toJSON(inputs.a)",
+			context: map[string]interface{}{"inputs": map[string]interface{}{"a": "<a & b>"}},
+			want:    &EvaluationResult{Value: "\"<a & b>\"", Type: &actionlint.StringType{}},
+		},
 		{
 			name:  "fcall - fromJson",
 			input: "fromJson('{\"foo\": 42}')",
 			want: &EvaluationResult{Value: ContextData{
 				"foo": float64(42),
-			}, Type: &actionlint.ObjectType{}},
+			}, Type: &actionlint.ObjectType{Props: map[string]actionlint.ExprType{
+				"foo": &actionlint.NumberType{},
+			}}},
 		},
 
 		{
@@ -265,7 +307,119 @@ func Test_Evaluate(t *testing.T) {
 		{
 			name:  "fcall - fromJson - empty string",
 			input: "fromJson('')",
-			want:  &EvaluationResult{Value: ContextData{}, Type: &actionlint.ObjectType{}},
+			want:  &EvaluationResult{Value: ContextData{}, Type: &actionlint.ObjectType{Props: map[string]actionlint.ExprType{}}},
+		},
+		{
+			name:    "fcall - contains string",
+			input:   "contains(github.ref, 'MAIN')",
+			context: map[string]interface{}{"github": map[string]interface{}{"ref": "refs/heads/main"}},
+			want:    &EvaluationResult{Value: true, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:  "fcall - contains array",
+			input: "contains(fromJSON('[\"push\", 1]'), 'push')",
+			want:  &EvaluationResult{Value: true, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:  "fcall - contains array not found",
+			input: "contains(fromJSON('[\"push\", 1]'), 'pu')",
+			want:  &EvaluationResult{Value: false, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:  "fcall - success",
+			input: "success()",
+			want:  &EvaluationResult{Value: true, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:  "fcall - failure",
+			input: "failure()",
+			want:  &EvaluationResult{Value: false, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:    "missing property",
+			input:   "input.foo",
+			context: map[string]interface{}{"input": map[string]interface{}{}},
+			want:    &EvaluationResult{Value: nil, Type: &actionlint.NullType{}},
+		},
+		{
+			name:    "array index out of range",
+			input:   "input[3]",
+			context: map[string]interface{}{"input": []interface{}{"a"}},
+			want:    &EvaluationResult{Value: nil, Type: &actionlint.NullType{}},
+		},
+		{
+			name:  "null literal",
+			input: "null",
+			want:  &EvaluationResult{Value: nil, Type: &actionlint.NullType{}},
+		},
+		{
+			name:  "wildcard access",
+			input: "inputs.*.name",
+			context: map[string]interface{}{"inputs": map[string]interface{}{
+				"a": map[string]interface{}{"name": "x"},
+				"b": map[string]interface{}{"name": "y"},
+				"c": map[string]interface{}{},
+			}},
+			want: &EvaluationResult{Value: []interface{}{"x", "y"}, Type: &actionlint.ArrayType{Elem: &actionlint.StringType{}, Deref: true}},
+		},
+		{
+			name:  "wildcard access - contains",
+			input: "contains(github.event.commits.*.message, 'fix')",
+			context: map[string]interface{}{"github": map[string]interface{}{"event": map[string]interface{}{
+				"commits": []interface{}{
+					map[string]interface{}{"message": "feat"},
+					map[string]interface{}{"message": "fix"},
+				},
+			}}},
+			want: &EvaluationResult{Value: true, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:    "unknown",
+			input:   "steps.a.outputs.b == 'x'",
+			context: map[string]interface{}{"steps": map[string]interface{}{"a": map[string]interface{}{"outputs": Unknown}}},
+			want:    &EvaluationResult{Value: Unknown, Type: &actionlint.AnyType{}},
+		},
+		{
+			name:    "unknown - truthiness decided by rhs of ||",
+			input:   "steps.a.outputs.b || 'x'",
+			context: map[string]interface{}{"steps": map[string]interface{}{"a": map[string]interface{}{"outputs": Unknown}}},
+			want:    &EvaluationResult{Value: Unknown, Type: &actionlint.AnyType{}, truthiness: boolPtr(true)},
+		},
+		{
+			name:    "unknown - truthiness decided by rhs of &&",
+			input:   "steps.a.outputs.b && ''",
+			context: map[string]interface{}{"steps": map[string]interface{}{"a": map[string]interface{}{"outputs": Unknown}}},
+			want:    &EvaluationResult{Value: Unknown, Type: &actionlint.AnyType{}, truthiness: boolPtr(false)},
+		},
+		{
+			name:    "unknown - negated decided truthiness",
+			input:   "!(steps.a.outputs.b || 'x')",
+			context: map[string]interface{}{"steps": map[string]interface{}{"a": map[string]interface{}{"outputs": Unknown}}},
+			want:    &EvaluationResult{Value: false, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:    "unknown - decided truthiness short circuits",
+			input:   "(steps.a.outputs.b || 'x') && 'y'",
+			context: map[string]interface{}{"steps": map[string]interface{}{"a": map[string]interface{}{"outputs": Unknown}}},
+			want:    &EvaluationResult{Value: "y", Type: &actionlint.StringType{}},
+		},
+		{
+			name:    "unknown - not decided by rhs of &&",
+			input:   "steps.a.outputs.b && 'x'",
+			context: map[string]interface{}{"steps": map[string]interface{}{"a": map[string]interface{}{"outputs": Unknown}}},
+			want:    &EvaluationResult{Value: Unknown, Type: &actionlint.AnyType{}},
+		},
+		{
+			name:    "unknown - decided by lhs of &&",
+			input:   "false && steps.a.outputs.b",
+			context: map[string]interface{}{"steps": map[string]interface{}{"a": map[string]interface{}{"outputs": Unknown}}},
+			want:    &EvaluationResult{Value: false, Type: &actionlint.BoolType{}},
+		},
+		{
+			name:    "unknown - hashFiles",
+			input:   "hashFiles('**/package-lock.json')",
+			context: map[string]interface{}{},
+			want:    &EvaluationResult{Value: Unknown, Type: &actionlint.AnyType{}},
 		},
 	}
 	for _, tt := range tests {
@@ -286,3 +440,69 @@ func Test_Evaluate(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateWithOptions_Status(t *testing.T) {
+	tests := []struct {
+		input  string
+		status Status
+		want   interface{}
+	}{
+		{input: "success()", status: StatusFailure, want: false},
+		{input: "failure()", status: StatusFailure, want: true},
+		{input: "cancelled()", status: StatusCancelled, want: true},
+		{input: "always()", status: StatusUnknown, want: true},
+		{input: "success()", status: StatusUnknown, want: Unknown},
+		{input: "success() || always()", status: StatusUnknown, want: Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input+" "+tt.status.String(), func(t *testing.T) {
+			lexer := actionlint.NewExprLexer(tt.input + "}}")
+			parser := actionlint.NewExprParser()
+			n, perr := parser.Parse(lexer)
+			if perr != nil {
+				t.Fatal(perr.Error())
+			}
+
+			got, err := EvaluateWithOptions(n, ContextData{}, EvaluateOptions{Status: tt.status})
+			if err != nil {
+				t.Fatalf("EvaluateWithOptions() error = %v", err)
+			}
+			if got.Value != tt.want {
+				t.Errorf("EvaluateWithOptions() = %v, want %v", got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Evaluate_FormatErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr string
+	}{
+		{"format('{0', 1)", `invalid format string "{0": unclosed placeholder`},
+		{"format('{1}', 1)", `invalid format string "{1}": no argument for placeholder "{1}"`},
+		{"format('{a}', 1)", `invalid format string "{a}": invalid placeholder "{a}"`},
+		{"format('{-0}', 1)", `invalid format string "{-0}": invalid placeholder "{-0}"`},
+		{"format('{}', 1)", `invalid format string "{}": invalid placeholder "{}"`},
+		{"format('0}', 1)", `invalid format string "0}": unescaped '}'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			lexer := actionlint.NewExprLexer(tt.input + "}}")
+			parser := actionlint.NewExprParser()
+			n, perr := parser.Parse(lexer)
+			if perr != nil {
+				t.Fatal(perr.Error())
+			}
+
+			_, err := Evaluate(n, ContextData{})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Evaluate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
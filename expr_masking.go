@@ -0,0 +1,232 @@
+package expr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// MaskedValue replaces secret values, like the runner does in logs
+const MaskedValue = "***"
+
+// Masker redacts secret values from strings, mirroring how the runner masks secrets in logs.
+// Besides the values themselves, their JSON-escaped and base64 encoded forms are masked.
+type Masker struct {
+	values []string
+}
+
+func NewMasker(values ...string) *Masker {
+	m := &Masker{}
+	for _, v := range values {
+		m.Add(v)
+	}
+
+	return m
+}
+
+// NewMaskerFromContext creates a masker for all values in the `secrets` context and the given
+// sensitive context paths.
+func NewMaskerFromContext(context ContextData, sensitivePaths ...string) *Masker {
+	m := NewMasker()
+
+	for _, p := range append([]string{"secrets"}, sensitivePaths...) {
+		if v, ok := lookupPath(context, p); ok {
+			m.addValue(v)
+		}
+	}
+
+	return m
+}
+
+// Add registers a secret value. Empty values are ignored.
+func (m *Masker) Add(value string) {
+	if value == "" {
+		return
+	}
+
+	// The JSON variant is escaped like toJSON escapes strings, which keeps characters like `<` and
+	// `&`
+	variants := []string{value, base64.StdEncoding.EncodeToString([]byte(value))}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err == nil {
+		js := strings.TrimSuffix(b.String(), "\n")
+		variants = append(variants, js[1:len(js)-1])
+	}
+
+	for _, v := range variants {
+		if !m.contains(v) {
+			m.values = append(m.values, v)
+		}
+	}
+
+	// Replace longer values first, so that secrets containing other secrets are fully masked
+	sort.SliceStable(m.values, func(i, j int) bool {
+		return len(m.values[i]) > len(m.values[j])
+	})
+}
+
+func (m *Masker) contains(value string) bool {
+	for _, v := range m.values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Masker) addValue(v interface{}) {
+	switch tv := v.(type) {
+	case ContextData:
+		for _, c := range tv {
+			m.addValue(c)
+		}
+
+	case []interface{}:
+		for _, c := range tv {
+			m.addValue(c)
+		}
+
+	case nil:
+
+	default:
+		m.Add((&EvaluationResult{Value: v, Type: getExprType(v)}).CoerceString())
+	}
+}
+
+// Mask replaces all registered secret values in the string
+func (m *Masker) Mask(s string) string {
+	for _, v := range m.values {
+		s = strings.ReplaceAll(s, v, MaskedValue)
+	}
+
+	return s
+}
+
+// MaskError returns an error with the registered secret values masked in its message
+func (m *Masker) MaskError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return errors.New(m.Mask(err.Error()))
+}
+
+// MaskResult returns the string representation of the result with secret values masked. Sensitive
+// results that don't contain any registered secret value are masked completely, since they are
+// derived from a secret in some other way.
+func (m *Masker) MaskResult(r *EvaluationResult) string {
+	s := r.CoerceString()
+	if !r.Sensitive {
+		return m.Mask(s)
+	}
+
+	if masked := m.Mask(s); masked != s {
+		return masked
+	}
+
+	return MaskedValue
+}
+
+// MaskTrace returns a copy of the trace with secret values masked. Values of sensitive nodes are
+// always masked.
+func (m *Masker) MaskTrace(t *TraceNode) *TraceNode {
+	c := *t
+	c.Expr = m.Mask(c.Expr)
+	c.Error = m.Mask(c.Error)
+
+	if c.Sensitive && c.Value != nil {
+		c.Value = m.MaskResult(&EvaluationResult{Value: c.Value, Type: getExprType(c.Value), Sensitive: true})
+	} else {
+		c.Value = m.maskValue(c.Value)
+	}
+
+	c.Coercions = make([]*TraceCoercion, len(t.Coercions))
+	for i, co := range t.Coercions {
+		cc := *co
+		cc.Operand = m.Mask(cc.Operand)
+		cc.Value = m.maskValue(cc.Value)
+		cc.Coerced = m.maskValue(cc.Coerced)
+		c.Coercions[i] = &cc
+	}
+
+	c.Children = make([]*TraceNode, len(t.Children))
+	for i, child := range t.Children {
+		c.Children[i] = m.MaskTrace(child)
+	}
+
+	return &c
+}
+
+func (m *Masker) maskValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		return m.Mask(s)
+	}
+
+	return v
+}
+
+// isSensitivePath returns whether values at the given context path are sensitive
+func (i *interpreter) isSensitivePath(path string) bool {
+	for _, p := range i.sensitivePaths {
+		if strings.EqualFold(path, p) || hasPrefixFold(path, p+".") || hasPrefixFold(path, p+"[") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsSensitivePath returns whether the value at the given path contains a sensitive value,
+// like `github` contains `github.token`. Wildcards in the path, like `github.*`, match any
+// property.
+func (i *interpreter) containsSensitivePath(path string) bool {
+	segments := strings.Split(path, ".")
+
+	for _, p := range i.sensitivePaths {
+		ps := strings.Split(p, ".")
+		if len(ps) < len(segments) {
+			continue
+		}
+
+		below := true
+		for k, s := range segments {
+			if s != "*" && !strings.EqualFold(s, ps[k]) {
+				below = false
+				break
+			}
+		}
+		if below {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// lookupPath returns the value at the given dot-separated context path
+func lookupPath(context ContextData, path string) (interface{}, bool) {
+	var v interface{} = context
+
+	for _, p := range strings.Split(path, ".") {
+		obj, ok := v.(ContextData)
+		if !ok {
+			return nil, false
+		}
+
+		if _, v, ok = LookupProperty(obj, p); !ok {
+			return nil, false
+		}
+	}
+
+	return v, true
+}
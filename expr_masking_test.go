@@ -0,0 +1,135 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rhysd/actionlint"
+)
+
+func TestEvaluateWithOptions_Sensitive(t *testing.T) {
+	context := ContextData{
+		"secrets": ContextData{"TOKEN": "s3cr3t"},
+		"github": ContextData{
+			"token":      "ghs_abc",
+			"event_name": "push",
+		},
+		"inputs": ContextData{"name": ""},
+	}
+
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"secrets.TOKEN", true},
+		{"secrets['token']", true},
+		{"secrets", true},
+		{"github.event_name", false},
+		{"github.token", true},
+		{"format('Bearer {0}', secrets.TOKEN)", true},
+		{"join(fromJSON('[\"a\"]'), secrets.TOKEN)", true},
+		{"toJSON(secrets)", true},
+		{"fromJSON(secrets.TOKEN)", true},
+		{"inputs.name || secrets.TOKEN", true},
+		{"secrets.TOKEN && github.event_name", false},
+		{"secrets.TOKEN == 'x'", false},
+		{"startsWith(secrets.TOKEN, 's')", false},
+		{"format('{0}', github.event_name)", false},
+		{"github", true},
+		{"toJSON(github)", true},
+		{"github.*", true},
+		{"toJSON(github.event_name)", false},
+		{"github['event_name']", false},
+		{"inputs", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			lexer := actionlint.NewExprLexer(tt.input + "}}")
+			parser := actionlint.NewExprParser()
+			n, perr := parser.Parse(lexer)
+			if perr != nil {
+				t.Fatal(perr.Error())
+			}
+
+			got, err := EvaluateWithOptions(n, context, EvaluateOptions{SensitivePaths: []string{"github.token"}})
+			if err != nil {
+				t.Fatalf("EvaluateWithOptions() error = %v", err)
+			}
+			if got.Sensitive != tt.want {
+				t.Errorf("EvaluateWithOptions() sensitive = %v, want %v", got.Sensitive, tt.want)
+			}
+		})
+	}
+}
+
+func TestMasker(t *testing.T) {
+	m := NewMaskerFromContext(ContextData{
+		"secrets": ContextData{"TOKEN": "s3cr3t", "PASSWORD": "p\"w", "KEY": "a<b&c\\d"},
+		"github":  ContextData{"token": "ghs_abc"},
+	}, "github.token")
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "token s3cr3t used", "token *** used"},
+		{"json escaped", `{"password": "p\"w"}`, `{"password": "***"}`},
+		{"json escaped html", `{"key": "a<b&c\\d"}`, `{"key": "***"}`},
+		{"base64", "czNjcjN0", "***"},
+		{"sensitive path", "ghs_abc", "***"},
+		{"no secret", "hello", "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Mask(tt.input); got != tt.want {
+				t.Errorf("Masker.Mask() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := m.MaskError(errors.New("invalid s3cr3t")).Error(); got != "invalid ***" {
+		t.Errorf("Masker.MaskError() = %v", got)
+	}
+
+	derived := &EvaluationResult{Value: float64(42), Type: &actionlint.NumberType{}, Sensitive: true}
+	if got := m.MaskResult(derived); got != MaskedValue {
+		t.Errorf("Masker.MaskResult() = %v, want %v", got, MaskedValue)
+	}
+
+	formatted := &EvaluationResult{Value: "Bearer s3cr3t", Type: &actionlint.StringType{}, Sensitive: true}
+	if got := m.MaskResult(formatted); got != "Bearer ***" {
+		t.Errorf("Masker.MaskResult() = %v, want %v", got, "Bearer ***")
+	}
+}
+
+func TestMasker_MaskTrace(t *testing.T) {
+	lexer := actionlint.NewExprLexer("format('{0}', fromJSON(secrets.CONFIG).port) == 'x'}}")
+	parser := actionlint.NewExprParser()
+	n, perr := parser.Parse(lexer)
+	if perr != nil {
+		t.Fatal(perr.Error())
+	}
+
+	context := ContextData{"secrets": ContextData{"CONFIG": `{"port": 8080}`}}
+	_, trace, err := ExplainEvaluate(n, context)
+	if err != nil {
+		t.Fatalf("ExplainEvaluate() error = %v", err)
+	}
+
+	masked := NewMaskerFromContext(context).MaskTrace(trace)
+
+	format := masked.Children[0]
+	if format.Value != MaskedValue {
+		t.Errorf("MaskTrace() format value = %v, want masked", format.Value)
+	}
+	if port := format.Children[1]; port.Value != MaskedValue {
+		t.Errorf("MaskTrace() port value = %v, want masked", port.Value)
+	}
+	if trace.Children[0].Value != "8080" {
+		t.Errorf("MaskTrace() modified the original trace")
+	}
+	if masked.Children[1].Value != "x" {
+		t.Errorf("MaskTrace() masked non-sensitive value %v", masked.Children[1].Value)
+	}
+}
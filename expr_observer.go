@@ -0,0 +1,94 @@
+package expr
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/rhysd/actionlint"
+)
+
+// Observer is notified about the progress of an evaluation. It can be used to instrument
+// evaluations, e.g. to audit which contexts are read or to profile function calls.
+type Observer interface {
+	// EnterNode is called before the node is evaluated
+	EnterNode(n actionlint.ExprNode)
+
+	// LeaveNode is called after the node was evaluated. Either result or err is set.
+	LeaveNode(n actionlint.ExprNode, result *EvaluationResult, err error)
+
+	// ContextAccess is called for every access of a context value. Path is the resolved path of
+	// the value, e.g. `secrets.TOKEN` for both `secrets.TOKEN` and `secrets['TOKEN']`.
+	ContextAccess(path string, result *EvaluationResult)
+
+	// FunctionCall is called after a function was called. Either result or err is set.
+	FunctionCall(name string, args []*EvaluationResult, result *EvaluationResult, err error, duration time.Duration)
+}
+
+// NopObserver implements Observer and ignores all notifications. It can be embedded in types
+// that only want to implement some of the methods.
+type NopObserver struct{}
+
+func (NopObserver) EnterNode(n actionlint.ExprNode) {}
+
+func (NopObserver) LeaveNode(n actionlint.ExprNode, result *EvaluationResult, err error) {}
+
+func (NopObserver) ContextAccess(path string, result *EvaluationResult) {}
+
+func (NopObserver) FunctionCall(name string, args []*EvaluationResult, result *EvaluationResult, err error, duration time.Duration) {
+}
+
+// EvaluateWithObserver evaluates the expression like Evaluate and notifies the given observer
+// while doing so.
+func EvaluateWithObserver(n actionlint.ExprNode, context ContextData, observer Observer) (*EvaluationResult, error) {
+	return EvaluateWithOptions(n, context, EvaluateOptions{Observer: observer})
+}
+
+// recordContextAccess records the resolved context path of a context access node, based on the
+// path of its receiver. Key is the accessed property or array index. Results for sensitive paths
+// are marked as sensitive.
+func (i *interpreter) recordContextAccess(n actionlint.ExprNode, receiver actionlint.ExprNode, key interface{}, result *EvaluationResult) {
+	var path string
+	if receiver == nil {
+		path = key.(string)
+	} else {
+		rp, ok := i.paths[receiver]
+		if !ok {
+			// Receiver is not a context, e.g. the result of `fromJSON`
+			return
+		}
+
+		switch k := key.(type) {
+		case string:
+			path = rp + "." + k
+		case int:
+			path = rp + "[" + strconv.Itoa(k) + "]"
+		}
+	}
+
+	i.paths[n] = path
+
+	// Values of contexts are sensitive if they are or contain a sensitive value, e.g. `github`
+	// contains `github.token`. Properties of them that don't aren't.
+	result.Sensitive = i.isSensitivePath(path) || i.containsSensitivePath(path)
+
+	if i.observer != nil {
+		i.observer.ContextAccess(path, result)
+	}
+}
+
+func (i *interpreter) call(name string, args []*EvaluationResult) (*EvaluationResult, error) {
+	f := fcall
+	if IsStatusFunction(name) {
+		f = i.statusCall
+	}
+
+	if i.observer == nil {
+		return f(name, args)
+	}
+
+	start := time.Now()
+	r, err := f(name, args)
+	i.observer.FunctionCall(name, args, r, err, time.Since(start))
+
+	return r, err
+}
@@ -0,0 +1,104 @@
+package expr
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rhysd/actionlint"
+)
+
+type recordingObserver struct {
+	NopObserver
+
+	entered  int
+	left     int
+	accesses []string
+	calls    []string
+}
+
+func (o *recordingObserver) EnterNode(n actionlint.ExprNode) {
+	o.entered++
+}
+
+func (o *recordingObserver) LeaveNode(n actionlint.ExprNode, result *EvaluationResult, err error) {
+	o.left++
+}
+
+func (o *recordingObserver) ContextAccess(path string, result *EvaluationResult) {
+	o.accesses = append(o.accesses, path)
+}
+
+func (o *recordingObserver) FunctionCall(name string, args []*EvaluationResult, result *EvaluationResult, err error, duration time.Duration) {
+	o.calls = append(o.calls, name+"="+traceValueString(result.Value))
+}
+
+func TestEvaluateWithObserver(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantAccesses []string
+		wantCalls    []string
+	}{
+		{
+			name:         "property access",
+			input:        "secrets.TOKEN",
+			wantAccesses: []string{"secrets", "secrets.TOKEN"},
+		},
+		{
+			name:         "index access",
+			input:        "secrets['TOKEN']",
+			wantAccesses: []string{"secrets", "secrets.TOKEN"},
+		},
+		{
+			name:         "array access",
+			input:        "github.event.commits[0].message",
+			wantAccesses: []string{"github", "github.event", "github.event.commits", "github.event.commits[0]", "github.event.commits[0].message"},
+		},
+		{
+			name:         "function call",
+			input:        "startsWith(github.ref, 'refs/tags/')",
+			wantAccesses: []string{"github", "github.ref"},
+			wantCalls:    []string{"startsWith=false"},
+		},
+		{
+			name:      "function result is not a context",
+			input:     "fromJSON('{\"a\": 1}').a",
+			wantCalls: []string{"fromJSON={...}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexer := actionlint.NewExprLexer(tt.input + "}}")
+			parser := actionlint.NewExprParser()
+			n, perr := parser.Parse(lexer)
+			if perr != nil {
+				t.Fatal(perr.Error())
+			}
+
+			o := &recordingObserver{}
+			_, err := EvaluateWithObserver(n, ContextData{
+				"secrets": ContextData{"TOKEN": "abc"},
+				"github": ContextData{
+					"ref": "refs/heads/main",
+					"event": ContextData{
+						"commits": []interface{}{ContextData{"message": "fix"}},
+					},
+				},
+			}, o)
+			if err != nil {
+				t.Fatalf("EvaluateWithObserver() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(o.accesses, tt.wantAccesses) {
+				t.Errorf("EvaluateWithObserver() accesses = %v, want %v", o.accesses, tt.wantAccesses)
+			}
+			if !reflect.DeepEqual(o.calls, tt.wantCalls) {
+				t.Errorf("EvaluateWithObserver() calls = %v, want %v", o.calls, tt.wantCalls)
+			}
+			if o.entered == 0 || o.entered != o.left {
+				t.Errorf("EvaluateWithObserver() entered %d nodes, left %d", o.entered, o.left)
+			}
+		})
+	}
+}
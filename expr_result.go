@@ -12,6 +12,26 @@ import (
 type EvaluationResult struct {
 	Value interface{}
 	Type  actionlint.ExprType
+
+	// Sensitive is set when the value is derived from the `secrets` context or another sensitive
+	// context path. Use a Masker to redact it before showing it to users.
+	Sensitive bool
+
+	// truthiness is the truthiness of an unknown value that is decided by other operands, like
+	// `steps.a.outputs.b || true`. Nil if the truthiness is unknown too.
+	truthiness *bool
+}
+
+// TruthinessKnown returns true if the value is known, or if the value is unknown but it's decided
+// whether it is truthy, like for `steps.a.outputs.b || true`. Conditions can be decided by
+// Truthy and Falsy then.
+func (ev *EvaluationResult) TruthinessKnown() bool {
+	return !ev.IsUnknown() || ev.truthiness != nil
+}
+
+// NewEvaluationResult returns the result for a context value, like the values of ContextData
+func NewEvaluationResult(value interface{}) *EvaluationResult {
+	return &EvaluationResult{Value: value, Type: getExprType(value)}
 }
 
 const (
@@ -63,6 +83,10 @@ func (ev *EvaluationResult) CoerceString() string {
 }
 
 func (ev *EvaluationResult) Falsy() bool {
+	if ev.truthiness != nil {
+		return !*ev.truthiness
+	}
+
 	switch ev.Type.(type) {
 	case *actionlint.NullType:
 		return true
@@ -139,6 +163,21 @@ func coerceTypes(li interface{}, ri interface{}) (lv interface{}, ltype actionli
 }
 
 func getExprType(value interface{}) actionlint.ExprType {
+	return exprTypes(nil).of(value)
+}
+
+// getElemType returns the type shared by all elements of the array, or any when the array is
+// empty or the element types differ.
+func getElemType(array []interface{}) actionlint.ExprType {
+	return exprTypes(nil).elemOf(array)
+}
+
+// exprTypes memoizes the types of objects by their identity, so nested objects of large contexts
+// like `github.event` are only typed once. Objects must not be modified while the types are in
+// use. A nil exprTypes doesn't memoize.
+type exprTypes map[uintptr]actionlint.ExprType
+
+func (c exprTypes) of(value interface{}) actionlint.ExprType {
 	if value == nil {
 		return &actionlint.NullType{}
 	}
@@ -152,34 +191,57 @@ func getExprType(value interface{}) actionlint.ExprType {
 		return &actionlint.StringType{}
 	}
 
-	t := reflect.TypeOf(value)
-	if t.Kind() == reflect.Array || t.Kind() == reflect.Slice {
-		// et := t.Elem()
+	switch tv := value.(type) {
+	case *unknownValue:
+		return &actionlint.AnyType{}
 
-		return &actionlint.ArrayType{
-			Elem: &actionlint.AnyType{}, // TODO: getExprTypeForType(et),
+	case ContextData:
+		key := reflect.ValueOf(tv).Pointer()
+		if ty, ok := c[key]; ok {
+			return ty
 		}
+
+		props := make(map[string]actionlint.ExprType, len(tv))
+		for k, v := range tv {
+			props[k] = c.of(v)
+		}
+
+		ty := &actionlint.ObjectType{Props: props}
+		if c != nil {
+			c[key] = ty
+		}
+
+		return ty
+
+	case []interface{}:
+		return &actionlint.ArrayType{Elem: c.elemOf(tv)}
+	}
+
+	t := reflect.TypeOf(value)
+	if t.Kind() == reflect.Array || t.Kind() == reflect.Slice {
+		return &actionlint.ArrayType{Elem: &actionlint.AnyType{}}
 	}
 
 	return &actionlint.ObjectType{
-		Props:  map[string]actionlint.ExprType{}, // TODO: Set types?
-		Mapped: &actionlint.AnyType{},            // TODO: Can we make this strict?
+		Props:  map[string]actionlint.ExprType{},
+		Mapped: &actionlint.AnyType{},
 	}
 }
 
-// func getExprTypeForType(t reflect.Type) actionlint.ExprType {
-// 	switch t.Kind() {
-// 	case reflect.Bool:
-// 		return &actionlint.BoolType{}
-
-// 	case reflect.String:
-// 		return &actionlint.StringType{}
+func (c exprTypes) elemOf(array []interface{}) actionlint.ExprType {
+	if len(array) == 0 {
+		return &actionlint.AnyType{}
+	}
 
-// 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-// 		return &actionlint.NumberType{}
+	et := c.of(array[0])
+	for _, v := range array[1:] {
+		if !reflect.DeepEqual(et, c.of(v)) {
+			return &actionlint.AnyType{}
+		}
+	}
 
-// 	}
-// }
+	return et
+}
 
 func (ev *EvaluationResult) Equals(rhs *EvaluationResult) bool {
 	lv, ltype, rv, rtype := coerceTypes(ev.Value, rhs.Value)
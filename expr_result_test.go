@@ -2,6 +2,7 @@ package expr
 
 import (
 	"math"
+	"reflect"
 	"strconv"
 	"testing"
 
@@ -72,3 +73,50 @@ func TestEvaluationResult_Falsy(t *testing.T) {
 		})
 	}
 }
+
+func Test_getExprType(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  actionlint.ExprType
+	}{
+		{"null", nil, &actionlint.NullType{}},
+		{"string", "test", &actionlint.StringType{}},
+		{"object", ContextData{"foo": float64(1), "bar": ContextData{"baz": true}}, &actionlint.ObjectType{
+			Props: map[string]actionlint.ExprType{
+				"foo": &actionlint.NumberType{},
+				"bar": &actionlint.ObjectType{Props: map[string]actionlint.ExprType{"baz": &actionlint.BoolType{}}},
+			},
+		}},
+		{"array", []interface{}{"a", "b"}, &actionlint.ArrayType{Elem: &actionlint.StringType{}}},
+		{"array mixed", []interface{}{"a", float64(1)}, &actionlint.ArrayType{Elem: &actionlint.AnyType{}}},
+		{"array empty", []interface{}{}, &actionlint.ArrayType{Elem: &actionlint.AnyType{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getExprType(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getExprType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_exprTypes(t *testing.T) {
+	pr := ContextData{"head": ContextData{"sha": "abc"}}
+	event := ContextData{"pull_request": pr, "number": float64(1)}
+
+	types := exprTypes{}
+
+	want := getExprType(event)
+	if got := types.of(event); !reflect.DeepEqual(got, want) {
+		t.Errorf("exprTypes.of() = %v, want %v", got, want)
+	}
+
+	// Nested objects are typed once, together with their parent
+	if got, want := types.of(pr), types.of(event).(*actionlint.ObjectType).Props["pull_request"]; got != want {
+		t.Errorf("exprTypes.of() = %p, want memoized %p", got, want)
+	}
+	if got, want := len(types), 3; got != want {
+		t.Errorf("len(exprTypes) = %v, want %v", got, want)
+	}
+}
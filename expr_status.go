@@ -0,0 +1,81 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rhysd/actionlint"
+)
+
+// Status is the status of the current job, or of the jobs it needs when evaluating job
+// conditions. It determines the result of the status check functions like `success()`.
+type Status int
+
+const (
+	StatusSuccess Status = iota
+	StatusFailure
+	StatusCancelled
+
+	// StatusSkipped is used when a job needed by the current job was skipped. Only `always()`
+	// evaluates to true.
+	StatusSkipped
+
+	// StatusUnknown is used when the status is only known at runtime. Status check functions other
+	// than `always()` evaluate to Unknown.
+	StatusUnknown
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusSuccess:
+		return "success"
+	case StatusFailure:
+		return "failure"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+var statusFunctions = map[string]func(s Status) bool{
+	"success":   func(s Status) bool { return s == StatusSuccess },
+	"failure":   func(s Status) bool { return s == StatusFailure },
+	"cancelled": func(s Status) bool { return s == StatusCancelled },
+	"always":    func(s Status) bool { return true },
+}
+
+// IsStatusFunction returns true if the given function is one of the status check functions
+func IsStatusFunction(name string) bool {
+	_, ok := statusFunctions[strings.ToLower(name)]
+	return ok
+}
+
+// UsesStatusFunction returns true if the expression calls any of the status check functions.
+// Conditions without one are implicitly combined with `success()`.
+func UsesStatusFunction(n actionlint.ExprNode) bool {
+	found := false
+	actionlint.VisitExprNode(n, func(node, _ actionlint.ExprNode, entering bool) {
+		if fn, ok := node.(*actionlint.FuncCallNode); ok && entering && IsStatusFunction(fn.Callee) {
+			found = true
+		}
+	})
+
+	return found
+}
+
+func (i *interpreter) statusCall(name string, args []*EvaluationResult) (*EvaluationResult, error) {
+	if len(args) != 0 {
+		return nil, errors.New(fmt.Sprintf("invalid number of arguments. expected 0, got %d", len(args)))
+	}
+
+	name = strings.ToLower(name)
+	if i.status == StatusUnknown && name != "always" {
+		return unknownResult(), nil
+	}
+
+	return &EvaluationResult{Value: statusFunctions[name](i.status), Type: &actionlint.BoolType{}}, nil
+}
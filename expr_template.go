@@ -0,0 +1,56 @@
+package expr
+
+import (
+	"strings"
+
+	"github.com/rhysd/actionlint"
+)
+
+// TemplateExpression is an expression embedded in a string using `${{ }}`
+type TemplateExpression struct {
+	Node actionlint.ExprNode
+
+	// Source is the expression between `${{` and `}}`
+	Source string
+
+	// Offset is the offset of `${{` in the string, End the offset after the closing `}}`
+	Offset int
+	End    int
+}
+
+// ParseTemplate parses all expressions embedded in the given string
+func ParseTemplate(s string) ([]*TemplateExpression, error) {
+	exprs := []*TemplateExpression{}
+
+	offset := 0
+	for {
+		idx := strings.Index(s[offset:], "${{")
+		if idx == -1 {
+			break
+		}
+
+		start := offset + idx
+		src := s[start+len("${{"):]
+
+		lexer := actionlint.NewExprLexer(src)
+		parser := actionlint.NewExprParser()
+		n, err := parser.Parse(lexer)
+		if err != nil {
+			return nil, err
+		}
+
+		// The lexer stops after the closing `}}`
+		end := start + len("${{") + lexer.Offset()
+
+		exprs = append(exprs, &TemplateExpression{
+			Node:   n,
+			Source: strings.TrimSpace(strings.TrimSuffix(s[start+len("${{"):end], "}}")),
+			Offset: start,
+			End:    end,
+		})
+
+		offset = end
+	}
+
+	return exprs, nil
+}
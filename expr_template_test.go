@@ -0,0 +1,35 @@
+package expr
+
+import (
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+	s := "echo ${{ github.event_name }} ${{format('}}', 1)}}"
+
+	got, err := ParseTemplate(s)
+	if err != nil {
+		t.Fatalf("ParseTemplate() error = %v", err)
+	}
+
+	want := []struct {
+		source string
+		text   string
+	}{
+		{"github.event_name", "${{ github.event_name }}"},
+		{"format('}}', 1)", "${{format('}}', 1)}}"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ParseTemplate() = %d expressions, want %d", len(got), len(want))
+	}
+
+	for i, w := range want {
+		if got[i].Source != w.source {
+			t.Errorf("ParseTemplate() source = %q, want %q", got[i].Source, w.source)
+		}
+		if text := s[got[i].Offset:got[i].End]; text != w.text {
+			t.Errorf("ParseTemplate() text = %q, want %q", text, w.text)
+		}
+	}
+}
@@ -0,0 +1,429 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rhysd/actionlint"
+)
+
+// TraceNode records the evaluation of a single node of an expression
+type TraceNode struct {
+	// Expr is the source representation of the evaluated node
+	Expr string `json:"expr"`
+
+	// Kind is the kind of the node, e.g. "compare" or "call"
+	Kind string `json:"kind"`
+
+	Span TraceSpan `json:"span"`
+
+	// Value and Type are the result of evaluating the node. Both are empty if the node was
+	// short-circuited or evaluation failed.
+	Value interface{} `json:"value"`
+	Type  string      `json:"type,omitempty"`
+
+	Error string `json:"error,omitempty"`
+
+	// Sensitive is set when the value is derived from secrets
+	Sensitive bool `json:"sensitive,omitempty"`
+
+	// ShortCircuited is set when the node was not evaluated since the result of a logical
+	// operator was already determined by its left operand
+	ShortCircuited bool `json:"short_circuited,omitempty"`
+
+	// Coercions lists the conversions applied to operands before comparing them or testing
+	// them for truthiness
+	Coercions []*TraceCoercion `json:"coercions,omitempty"`
+
+	Children []*TraceNode `json:"children,omitempty"`
+}
+
+// TraceSpan is the location of a node in the expression source. End is the offset after the last
+// token of the node and is best-effort, closing parentheses and brackets are assumed to directly
+// follow the last argument or index.
+type TraceSpan struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	End    int `json:"end"`
+}
+
+// TraceCoercion describes how an operand was converted
+type TraceCoercion struct {
+	// Operand is the source representation of the converted operand
+	Operand string      `json:"operand"`
+	Value   interface{} `json:"value"`
+	Coerced interface{} `json:"coerced"`
+	Rule    string      `json:"rule"`
+}
+
+// ExplainEvaluate evaluates the expression like Evaluate, and additionally returns a trace of every
+// visited node. The trace is returned even if evaluation fails.
+func ExplainEvaluate(n actionlint.ExprNode, context ContextData) (*EvaluationResult, *TraceNode, error) {
+	root := &TraceNode{}
+	i := newInterpreter(context)
+	i.trace = root
+
+	r, err := i.evaluate(n)
+
+	return r, root.Children[0], err
+}
+
+func newTraceNode(n actionlint.ExprNode) *TraceNode {
+	t := n.Token()
+
+	return &TraceNode{
+		Expr: exprString(n),
+		Kind: exprKind(n),
+		Span: TraceSpan{
+			Offset: t.Offset,
+			Line:   t.Line,
+			Column: t.Column,
+			End:    exprEnd(n),
+		},
+	}
+}
+
+func (t *TraceNode) setResult(r *EvaluationResult, err error) {
+	if err != nil {
+		t.Error = err.Error()
+		return
+	}
+
+	t.Value = r.Value
+	t.Type = typeString(r.Type)
+	t.Sensitive = r.Sensitive
+}
+
+func (i *interpreter) traceShortCircuit(n actionlint.ExprNode) {
+	if i.trace == nil {
+		return
+	}
+
+	t := newTraceNode(n)
+	t.ShortCircuited = true
+	i.trace.Children = append(i.trace.Children, t)
+}
+
+func (i *interpreter) traceTruthiness(n actionlint.ExprNode, r *EvaluationResult) {
+	if i.trace == nil {
+		return
+	}
+
+	var rule string
+	switch r.Type.(type) {
+	case *actionlint.BoolType:
+		return
+	case *actionlint.NullType:
+		rule = "null→bool, null is falsy"
+	case *actionlint.NumberType:
+		rule = "number→bool, 0 and NaN are falsy"
+	case *actionlint.StringType:
+		rule = "string→bool, empty string is falsy"
+	default:
+		rule = fmt.Sprintf("%s→bool, objects and arrays are truthy", typeString(r.Type))
+	}
+
+	i.trace.Coercions = append(i.trace.Coercions, &TraceCoercion{
+		Operand: exprString(n),
+		Value:   r.Value,
+		Coerced: r.Truthy(),
+		Rule:    rule,
+	})
+}
+
+func (i *interpreter) traceComparison(n *actionlint.CompareOpNode, left, right *EvaluationResult) {
+	if i.trace == nil {
+		return
+	}
+
+	lv, ltype, rv, rtype := coerceTypes(left.Value, right.Value)
+
+	// Operands of different types that can't be converted, like objects, aren't coerced
+	if ltype.String() != rtype.String() {
+		return
+	}
+
+	if rule, ok := coercionRule(left.Value, ltype); ok {
+		i.trace.Coercions = append(i.trace.Coercions, &TraceCoercion{
+			Operand: exprString(n.Left),
+			Value:   left.Value,
+			Coerced: lv,
+			Rule:    rule,
+		})
+	}
+
+	if rule, ok := coercionRule(right.Value, rtype); ok {
+		i.trace.Coercions = append(i.trace.Coercions, &TraceCoercion{
+			Operand: exprString(n.Right),
+			Value:   right.Value,
+			Coerced: rv,
+			Rule:    rule,
+		})
+	}
+}
+
+// typeString returns the name of the type like ExprType.String, with the properties of objects
+// in sort order for a stable trace
+func typeString(ty actionlint.ExprType) string {
+	switch t := ty.(type) {
+	case *actionlint.ObjectType:
+		if !t.IsStrict() {
+			break
+		}
+
+		ps := make([]string, 0, len(t.Props))
+		for n, p := range t.Props {
+			ps = append(ps, fmt.Sprintf("%s: %s", n, typeString(p)))
+		}
+		sort.Strings(ps)
+
+		return fmt.Sprintf("{%s}", strings.Join(ps, "; "))
+
+	case *actionlint.ArrayType:
+		return fmt.Sprintf("array<%s>", typeString(t.Elem))
+	}
+
+	return ty.String()
+}
+
+// coercionRule describes the rule used to convert value to a value of type to
+func coercionRule(value interface{}, to actionlint.ExprType) (string, bool) {
+	from := getExprType(value)
+	if from.String() == to.String() {
+		return "", false
+	}
+
+	switch from.(type) {
+	case *actionlint.StringType:
+		return fmt.Sprintf("string→%s via parseNumber", to.String()), true
+	case *actionlint.BoolType:
+		return fmt.Sprintf("bool→%s, true is 1 and false is 0", to.String()), true
+	case *actionlint.NullType:
+		return fmt.Sprintf("null→%s, null is 0", to.String()), true
+	}
+
+	return fmt.Sprintf("%s→%s", from.String(), to.String()), true
+}
+
+// String renders the trace as indented text, one node per line
+func (t *TraceNode) String() string {
+	var b strings.Builder
+	t.write(&b, 0)
+	return b.String()
+}
+
+func (t *TraceNode) write(b *strings.Builder, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	b.WriteString(indent)
+	b.WriteString(t.Expr)
+
+	switch {
+	case t.ShortCircuited:
+		b.WriteString(" (short-circuited)")
+	case t.Error != "":
+		b.WriteString(" => error: ")
+		b.WriteString(t.Error)
+	default:
+		fmt.Fprintf(b, " => %s (%s)", traceValueString(t.Value), t.Type)
+	}
+
+	b.WriteString("\n")
+
+	for _, c := range t.Coercions {
+		fmt.Fprintf(b, "%s  ~ %s: %s -> %s (%s)\n", indent, c.Operand, traceValueString(c.Value), traceValueString(c.Coerced), c.Rule)
+	}
+
+	for _, c := range t.Children {
+		c.write(b, depth+1)
+	}
+}
+
+// MarshalJSON implements json.Marshaler. Numbers that can't be represented in JSON, like NaN, are
+// rendered as strings.
+func (t *TraceNode) MarshalJSON() ([]byte, error) {
+	type traceNode TraceNode
+
+	c := traceNode(*t)
+	c.Value = jsonSafeValue(c.Value)
+
+	return json.Marshal(&c)
+}
+
+// MarshalJSON implements json.Marshaler
+func (c *TraceCoercion) MarshalJSON() ([]byte, error) {
+	type traceCoercion TraceCoercion
+
+	cc := traceCoercion(*c)
+	cc.Value = jsonSafeValue(cc.Value)
+	cc.Coerced = jsonSafeValue(cc.Coerced)
+
+	return json.Marshal(&cc)
+}
+
+func jsonSafeValue(v interface{}) interface{} {
+	if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return strconv.FormatFloat(f, 'G', 15, 64)
+	}
+
+	return v
+}
+
+func traceValueString(v interface{}) string {
+	r := &EvaluationResult{Value: v, Type: getExprType(v)}
+
+	switch r.Type.(type) {
+	case *actionlint.StringType:
+		return "'" + strings.ReplaceAll(r.CoerceString(), "'", "''") + "'"
+	case *actionlint.NullType:
+		return "null"
+	case *actionlint.ObjectType:
+		return "{...}"
+	case *actionlint.ArrayType:
+		return "[...]"
+	}
+
+	return r.CoerceString()
+}
+
+// exprString renders the node as expression source
+func exprString(n actionlint.ExprNode) string {
+	switch tn := n.(type) {
+	case *actionlint.VariableNode:
+		return tn.Name
+	case *actionlint.NullNode:
+		return "null"
+	case *actionlint.BoolNode:
+		return strconv.FormatBool(tn.Value)
+	case *actionlint.IntNode:
+		return strconv.Itoa(tn.Value)
+	case *actionlint.FloatNode:
+		return strconv.FormatFloat(tn.Value, 'G', 15, 64)
+	case *actionlint.StringNode:
+		return "'" + strings.ReplaceAll(tn.Value, "'", "''") + "'"
+	case *actionlint.ObjectDerefNode:
+		return exprString(tn.Receiver) + "." + tn.Property
+	case *actionlint.ArrayDerefNode:
+		return exprString(tn.Receiver) + ".*"
+	case *actionlint.IndexAccessNode:
+		return exprString(tn.Operand) + "[" + exprString(tn.Index) + "]"
+	case *actionlint.NotOpNode:
+		return "!" + operandString(tn.Operand, n)
+	case *actionlint.CompareOpNode:
+		return operandString(tn.Left, n) + " " + compareOpString(tn.Kind) + " " + operandString(tn.Right, n)
+	case *actionlint.LogicalOpNode:
+		return operandString(tn.Left, n) + " " + tn.Kind.String() + " " + operandString(tn.Right, n)
+	case *actionlint.FuncCallNode:
+		args := make([]string, len(tn.Args))
+		for i, a := range tn.Args {
+			args[i] = exprString(a)
+		}
+		return tn.Callee + "(" + strings.Join(args, ", ") + ")"
+	}
+
+	return ""
+}
+
+// operandString renders the operand of an operator, with parentheses if the operand binds less
+// tightly than the operator
+func operandString(operand, operator actionlint.ExprNode) string {
+	if precedence(operand) < precedence(operator) {
+		return "(" + exprString(operand) + ")"
+	}
+
+	return exprString(operand)
+}
+
+func precedence(n actionlint.ExprNode) int {
+	switch tn := n.(type) {
+	case *actionlint.LogicalOpNode:
+		if tn.Kind == actionlint.LogicalOpNodeKindOr {
+			return 1
+		}
+		return 2
+	case *actionlint.CompareOpNode:
+		if tn.Kind == actionlint.CompareOpNodeKindEq || tn.Kind == actionlint.CompareOpNodeKindNotEq {
+			return 3
+		}
+		return 4
+	case *actionlint.NotOpNode:
+		return 5
+	}
+
+	return 6
+}
+
+func compareOpString(k actionlint.CompareOpNodeKind) string {
+	switch k {
+	case actionlint.CompareOpNodeKindLess:
+		return "<"
+	case actionlint.CompareOpNodeKindLessEq:
+		return "<="
+	case actionlint.CompareOpNodeKindGreater:
+		return ">"
+	case actionlint.CompareOpNodeKindGreaterEq:
+		return ">="
+	case actionlint.CompareOpNodeKindEq:
+		return "=="
+	case actionlint.CompareOpNodeKindNotEq:
+		return "!="
+	}
+
+	return "?"
+}
+
+func exprKind(n actionlint.ExprNode) string {
+	switch n.(type) {
+	case *actionlint.NullNode, *actionlint.BoolNode, *actionlint.IntNode, *actionlint.FloatNode, *actionlint.StringNode:
+		return "literal"
+	case *actionlint.VariableNode:
+		return "context"
+	case *actionlint.ObjectDerefNode:
+		return "property"
+	case *actionlint.ArrayDerefNode:
+		return "filter"
+	case *actionlint.IndexAccessNode:
+		return "index"
+	case *actionlint.NotOpNode:
+		return "not"
+	case *actionlint.CompareOpNode:
+		return "compare"
+	case *actionlint.LogicalOpNode:
+		return "logical"
+	case *actionlint.FuncCallNode:
+		return "call"
+	}
+
+	return "unknown"
+}
+
+// exprEnd returns the offset after the last token of the node
+func exprEnd(n actionlint.ExprNode) int {
+	switch tn := n.(type) {
+	case *actionlint.ObjectDerefNode:
+		return exprEnd(tn.Receiver) + len(".") + len(tn.Property)
+	case *actionlint.ArrayDerefNode:
+		return exprEnd(tn.Receiver) + len(".*")
+	case *actionlint.IndexAccessNode:
+		return exprEnd(tn.Index) + len("]")
+	case *actionlint.NotOpNode:
+		return exprEnd(tn.Operand)
+	case *actionlint.CompareOpNode:
+		return exprEnd(tn.Right)
+	case *actionlint.LogicalOpNode:
+		return exprEnd(tn.Right)
+	case *actionlint.FuncCallNode:
+		if len(tn.Args) == 0 {
+			return tn.Token().Offset + len(tn.Callee) + len("()")
+		}
+		return exprEnd(tn.Args[len(tn.Args)-1]) + len(")")
+	}
+
+	t := n.Token()
+	return t.Offset + len(t.Value)
+}
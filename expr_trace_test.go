@@ -0,0 +1,130 @@
+package expr
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rhysd/actionlint"
+)
+
+func TestExplainEvaluate(t *testing.T) {
+	lexer := actionlint.NewExprLexer("github.event_name == 'push' || github.run_number > '5'}}")
+	parser := actionlint.NewExprParser()
+	n, perr := parser.Parse(lexer)
+	if perr != nil {
+		t.Fatal(perr.Error())
+	}
+
+	got, trace, err := ExplainEvaluate(n, ContextData{
+		"github": ContextData{
+			"event_name": "pull_request",
+			"run_number": float64(7),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ExplainEvaluate() error = %v", err)
+	}
+	if got.Value != true {
+		t.Errorf("ExplainEvaluate() = %v, want true", got.Value)
+	}
+
+	want := strings.Join([]string{
+		"github.event_name == 'push' || github.run_number > '5' => true (bool)",
+		"  github.event_name == 'push' => false (bool)",
+		"    github.event_name => 'pull_request' (string)",
+		"      github => {...} ({event_name: string; run_number: number})",
+		"    'push' => 'push' (string)",
+		"  github.run_number > '5' => true (bool)",
+		"    ~ '5': '5' -> 5 (string→number via parseNumber)",
+		"    github.run_number => 7 (number)",
+		"      github => {...} ({event_name: string; run_number: number})",
+		"    '5' => '5' (string)",
+		"",
+	}, "\n")
+
+	if trace.String() != want {
+		t.Errorf("ExplainEvaluate() trace =\n%v\nwant\n%v", trace.String(), want)
+	}
+
+	if trace.Span.Offset != 0 || trace.Span.End != 54 {
+		t.Errorf("ExplainEvaluate() span = %v, want 0-54", trace.Span)
+	}
+}
+
+func TestExplainEvaluate_ShortCircuit(t *testing.T) {
+	lexer := actionlint.NewExprLexer("false && fromJSON('{}') || 'a' == 'NaN'}}")
+	parser := actionlint.NewExprParser()
+	n, perr := parser.Parse(lexer)
+	if perr != nil {
+		t.Fatal(perr.Error())
+	}
+
+	_, trace, err := ExplainEvaluate(n, ContextData{})
+	if err != nil {
+		t.Fatalf("ExplainEvaluate() error = %v", err)
+	}
+
+	and := trace.Children[0]
+	if len(and.Children) != 2 || !and.Children[1].ShortCircuited {
+		t.Fatalf("ExplainEvaluate() rhs of && not short-circuited: %v", and)
+	}
+	if len(and.Children[1].Children) != 0 {
+		t.Errorf("ExplainEvaluate() short-circuited node has children")
+	}
+
+	b, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(b), `"short_circuited":true`) {
+		t.Errorf("json.Marshal() = %s, missing short-circuited node", b)
+	}
+}
+
+func TestExplainEvaluate_NaN(t *testing.T) {
+	lexer := actionlint.NewExprLexer("'abc' < 1}}")
+	parser := actionlint.NewExprParser()
+	n, perr := parser.Parse(lexer)
+	if perr != nil {
+		t.Fatal(perr.Error())
+	}
+
+	_, trace, err := ExplainEvaluate(n, ContextData{})
+	if err != nil {
+		t.Fatalf("ExplainEvaluate() error = %v", err)
+	}
+
+	if len(trace.Coercions) != 1 || trace.Coercions[0].Rule != "string→number via parseNumber" {
+		t.Fatalf("ExplainEvaluate() coercions = %v", trace.Coercions)
+	}
+
+	b, err := json.Marshal(trace)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(b), `"coerced":"NaN"`) {
+		t.Errorf("json.Marshal() = %s, want NaN as string", b)
+	}
+}
+
+func TestExplainEvaluate_NoCoercion(t *testing.T) {
+	lexer := actionlint.NewExprLexer("github == 'push'}}")
+	parser := actionlint.NewExprParser()
+	n, perr := parser.Parse(lexer)
+	if perr != nil {
+		t.Fatal(perr.Error())
+	}
+
+	got, trace, err := ExplainEvaluate(n, ContextData{"github": ContextData{"event_name": "push"}})
+	if err != nil {
+		t.Fatalf("ExplainEvaluate() error = %v", err)
+	}
+	if got.Value != false {
+		t.Errorf("ExplainEvaluate() = %v, want false", got.Value)
+	}
+
+	if len(trace.Coercions) != 0 {
+		t.Errorf("ExplainEvaluate() coercions = %v, want none", trace.Coercions)
+	}
+}
@@ -0,0 +1,84 @@
+package expr
+
+import (
+	"github.com/rhysd/actionlint"
+)
+
+type unknownValue struct{}
+
+// Unknown is a placeholder for context values that are not known before a workflow runs, like the
+// outputs of steps. Expressions depending on an unknown value evaluate to Unknown unless the
+// result is decided by other operands, e.g. `false && steps.a.outputs.b`. The truthiness of
+// `steps.a.outputs.b || true` is known, see EvaluationResult.TruthinessKnown, its value isn't.
+var Unknown interface{} = &unknownValue{}
+
+// IsUnknown returns true if the value of the result cannot be determined before running
+func (ev *EvaluationResult) IsUnknown() bool {
+	return ev.Value == Unknown
+}
+
+// unknownResult returns an unknown result, which is sensitive if any of the operands is
+func unknownResult(operands ...*EvaluationResult) *EvaluationResult {
+	r := &EvaluationResult{Value: Unknown, Type: &actionlint.AnyType{}}
+	for _, o := range operands {
+		r.Sensitive = r.Sensitive || o.Sensitive
+	}
+
+	return r
+}
+
+// ContainsUnknown returns true if the value is Unknown or any of its nested values is
+func ContainsUnknown(v interface{}) bool {
+	switch tv := v.(type) {
+	case *unknownValue:
+		return true
+
+	case ContextData:
+		for _, p := range tv {
+			if ContainsUnknown(p) {
+				return true
+			}
+		}
+
+	case []interface{}:
+		for _, e := range tv {
+			if ContainsUnknown(e) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// evaluateUnknownLogicalOp evaluates a logical operator whose left operand is unknown. The value
+// of the result stays unknown, since it's one of the operands, but its truthiness is known if the
+// right operand decides it regardless of the left one.
+func (i *interpreter) evaluateUnknownLogicalOp(tn *actionlint.LogicalOpNode, left *EvaluationResult) (*EvaluationResult, error) {
+	right, err := i.evaluate(tn.Right)
+	if err != nil {
+		return nil, err
+	}
+
+	r := unknownResult(left, right)
+	if !right.TruthinessKnown() {
+		return r, nil
+	}
+
+	switch tn.Kind {
+	case actionlint.LogicalOpNodeKindAnd:
+		// `x && false` is falsy whatever x is
+		if right.Falsy() {
+			r.truthiness = new(bool)
+		}
+
+	case actionlint.LogicalOpNodeKindOr:
+		// `x || true` is truthy whatever x is
+		if right.Truthy() {
+			truthy := true
+			r.truthiness = &truthy
+		}
+	}
+
+	return r, nil
+}
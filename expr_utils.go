@@ -79,3 +79,26 @@ func convertToNumber(v interface{}) float64 {
 
 	return math.NaN()
 }
+
+// LookupProperty returns the value of the given property. Property names are case-insensitive like
+// on GitHub, an exact match is preferred. Of several properties only differing in case, the first
+// in sort order is used for a stable result. The returned key is the name of the property as
+// stored in the object, or the given name if the property doesn't exist.
+func LookupProperty(obj ContextData, name string) (string, interface{}, bool) {
+	if v, ok := obj[name]; ok {
+		return name, v, true
+	}
+
+	key, found := name, false
+	for k := range obj {
+		if strings.EqualFold(k, name) && (!found || k < key) {
+			key, found = k, true
+		}
+	}
+
+	if !found {
+		return name, nil, false
+	}
+
+	return key, obj[key], true
+}
@@ -85,3 +85,31 @@ func Test_parseNumber(t *testing.T) {
 		})
 	}
 }
+
+func Test_lookupProperty(t *testing.T) {
+	obj := ContextData{"Name": "a", "NAME": "b", "name2": "c", "Other": "d"}
+
+	tests := []struct {
+		name    string
+		prop    string
+		wantKey string
+		want    interface{}
+		wantOk  bool
+	}{
+		{"exact match", "NAME", "NAME", "b", true},
+		{"case insensitive", "other", "Other", "d", true},
+		{"ambiguous uses first in sort order", "name", "NAME", "b", true},
+		{"missing", "missing", "missing", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order is random, repeat to catch unstable results
+			for i := 0; i < 20; i++ {
+				key, got, ok := LookupProperty(obj, tt.prop)
+				if key != tt.wantKey || got != tt.want || ok != tt.wantOk {
+					t.Fatalf("LookupProperty() = %v, %v, %v, want %v, %v, %v", key, got, ok, tt.wantKey, tt.want, tt.wantOk)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package git computes the changes of pushes and pull requests from a local git repository, the
+// way GitHub computes them for path filters. It runs the `git` executable and doesn't access the
+// network.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// run runs git in the given repository and returns its standard output
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+// ChangedFilesPush returns the files changed by a push from the before to the after commit, like
+// GitHub compares `github.event.before` and `github.event.after`.
+func ChangedFilesPush(dir string, before string, after string) ([]string, error) {
+	return diff(dir, before+".."+after)
+}
+
+// ChangedFilesPullRequest returns the files changed by a pull request merging head into base.
+// Like on GitHub, head is compared with the merge base of both commits, so changes on base made
+// after head branched off are not included.
+func ChangedFilesPullRequest(dir string, base string, head string) ([]string, error) {
+	return diff(dir, base+"..."+head)
+}
+
+// diff returns the files changed in the given revision range. Renamed files are listed with both
+// their old and new names. Revisions starting with `-` aren't taken as options.
+func diff(dir string, revisions string) ([]string, error) {
+	out, err := run(dir, "diff", "--name-status", "-z", "-M", "--end-of-options", revisions, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	seen := map[string]bool{}
+	add := func(f string) {
+		if !seen[f] {
+			seen[f] = true
+			files = append(files, f)
+		}
+	}
+
+	fields := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
+			continue
+		}
+
+		switch status[0] {
+		case 'R':
+			// Renames are followed by the old and the new name
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("unexpected output of git diff: %q", out)
+			}
+			add(fields[i+1])
+			add(fields[i+2])
+			i += 2
+
+		case 'C':
+			// Copies are followed by the source and the new file, only the new file changed
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("unexpected output of git diff: %q", out)
+			}
+			add(fields[i+2])
+			i += 2
+
+		default:
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("unexpected output of git diff: %q", out)
+			}
+			add(fields[i+1])
+			i++
+		}
+	}
+
+	return files, nil
+}
+
+// CurrentBranch returns the name of the checked out branch
+func CurrentBranch(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// ResolveCommit returns the SHA of the commit the revision points to
+func ResolveCommit(dir string, revision string) (string, error) {
+	out, err := run(dir, "rev-parse", "--verify", "--end-of-options", revision+"^{commit}")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,121 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testRepo creates a repository in a temporary directory
+func testRepo(t *testing.T) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"config", "user.email", "test@example.com"},
+		{"config", "user.name", "test"},
+		{"config", "commit.gpgsign", "false"},
+	} {
+		if _, err := run(dir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	for _, a := range [][]string{append([]string{"add", "-A"}, args...), {"commit", "-q", "-m", "commit"}} {
+		if _, err := run(dir, a...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := testRepo(t)
+
+	writeFile(t, dir, "README.md", "readme\n")
+	writeFile(t, dir, "src/old.go", "package src\n\nfunc Old() {}\n")
+	writeFile(t, dir, "docs/a.md", "a\n")
+	commit(t, dir)
+
+	if _, err := run(dir, "checkout", "-q", "-b", "feature"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := run(dir, "mv", "src/old.go", "src/new.go"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "docs/b.md", "b\n")
+	if err := os.Remove(filepath.Join(dir, "docs/a.md")); err != nil {
+		t.Fatal(err)
+	}
+	commit(t, dir)
+
+	// Changes on main after the feature branched off are not part of the pull request
+	if _, err := run(dir, "checkout", "-q", "main"); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "README.md", "changed\n")
+	commit(t, dir)
+
+	got, err := ChangedFilesPullRequest(dir, "main", "feature")
+	if err != nil {
+		t.Fatalf("ChangedFilesPullRequest() error = %v", err)
+	}
+	want := []string{"docs/a.md", "docs/b.md", "src/old.go", "src/new.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ChangedFilesPullRequest() = %v, want %v", got, want)
+	}
+
+	got, err = ChangedFilesPush(dir, "main~1", "main")
+	if err != nil {
+		t.Fatalf("ChangedFilesPush() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"README.md"}) {
+		t.Errorf("ChangedFilesPush() = %v, want [README.md]", got)
+	}
+
+	branch, err := CurrentBranch(dir)
+	if err != nil || branch != "main" {
+		t.Errorf("CurrentBranch() = %v, %v, want main", branch, err)
+	}
+
+	if _, err := ResolveCommit(dir, "does-not-exist"); err == nil {
+		t.Errorf("ResolveCommit() error = nil, want error")
+	}
+
+	// Revisions aren't taken as options
+	out := filepath.Join(t.TempDir(), "out")
+	if _, err := ChangedFilesPullRequest(dir, "--output="+out, "feature"); err == nil {
+		t.Errorf("ChangedFilesPullRequest() error = nil, want error for an option as base")
+	}
+	if _, err := ResolveCommit(dir, "--output="+out); err == nil {
+		t.Errorf("ResolveCommit() error = nil, want error for an option")
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Errorf("git wrote to %s", out)
+	}
+}
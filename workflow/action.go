@@ -0,0 +1,259 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+	"gopkg.in/yaml.v3"
+)
+
+// Action is the metadata of an action, parsed from its `action.yml`. actionlint v1.6.10 only
+// parses the inputs and outputs of actions, not the steps of composite actions.
+type Action struct {
+	Name string
+
+	// Inputs are the declared inputs by name in lower case
+	Inputs map[string]*ActionInput
+
+	// Outputs are the declared outputs by name in lower case
+	Outputs map[string]*ActionOutput
+
+	Runs *ActionRuns
+}
+
+// ActionInput is an input declared by an action
+type ActionInput struct {
+	Description string
+	Required    bool
+
+	// Default is the default value, which may contain expressions. Nil if there is none.
+	Default *string
+}
+
+// ActionOutput is an output declared by an action
+type ActionOutput struct {
+	Description string
+
+	// Value is the value of an output of a composite action, which may contain expressions
+	Value string
+}
+
+// ActionRuns describes how the action runs
+type ActionRuns struct {
+	// Using is `composite`, `docker`, or the node runtime like `node20`
+	Using string
+
+	// Steps are the steps of a composite action
+	Steps []*actionlint.Step
+}
+
+// actionFile is the YAML structure of an `action.yml`
+type actionFile struct {
+	Name   string `yaml:"name"`
+	Inputs map[string]struct {
+		Description string  `yaml:"description"`
+		Required    bool    `yaml:"required"`
+		Default     *string `yaml:"default"`
+	} `yaml:"inputs"`
+	Outputs map[string]struct {
+		Description string `yaml:"description"`
+		Value       string `yaml:"value"`
+	} `yaml:"outputs"`
+	Runs struct {
+		Using string    `yaml:"using"`
+		Steps yaml.Node `yaml:"steps"`
+	} `yaml:"runs"`
+}
+
+// ParseAction parses the metadata of an action
+func ParseAction(src []byte) (*Action, error) {
+	var f actionFile
+	if err := yaml.Unmarshal(src, &f); err != nil {
+		return nil, err
+	}
+
+	if f.Runs.Using == "" {
+		return nil, errors.New(`"runs.using" is missing`)
+	}
+
+	a := &Action{
+		Name:    f.Name,
+		Inputs:  map[string]*ActionInput{},
+		Outputs: map[string]*ActionOutput{},
+		Runs:    &ActionRuns{Using: f.Runs.Using},
+	}
+
+	for name, in := range f.Inputs {
+		a.Inputs[strings.ToLower(name)] = &ActionInput{Description: in.Description, Required: in.Required, Default: in.Default}
+	}
+
+	for name, o := range f.Outputs {
+		a.Outputs[strings.ToLower(name)] = &ActionOutput{Description: o.Description, Value: o.Value}
+	}
+
+	if f.Runs.Using == "composite" {
+		steps, err := parseCompositeSteps(&f.Runs.Steps)
+		if err != nil {
+			return nil, err
+		}
+		a.Runs.Steps = steps
+	}
+
+	return a, nil
+}
+
+// parseCompositeSteps parses the steps of a composite action with actionlint, as the steps of a
+// job of a generated workflow
+func parseCompositeSteps(steps *yaml.Node) ([]*actionlint.Step, error) {
+	if steps.Kind == 0 {
+		return nil, errors.New(`"runs.steps" is missing in composite action`)
+	}
+
+	scalar := func(v string) *yaml.Node { return &yaml.Node{Kind: yaml.ScalarNode, Value: v} }
+	mapping := func(nodes ...*yaml.Node) *yaml.Node { return &yaml.Node{Kind: yaml.MappingNode, Content: nodes} }
+
+	doc := mapping(
+		scalar("on"), scalar("push"),
+		scalar("jobs"), mapping(
+			scalar("composite"), mapping(
+				scalar("runs-on"), scalar("ubuntu-latest"),
+				scalar("steps"), steps,
+			),
+		),
+	)
+
+	src, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	w, errs := actionlint.Parse(src)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid steps: %s", errs[0].Message)
+	}
+
+	var parsed yaml.Node
+	if err := yaml.Unmarshal(src, &parsed); err == nil {
+		restoreEnvNames(w, &parsed)
+	}
+
+	return w.Jobs["composite"].Steps, nil
+}
+
+// LoadAction parses the `action.yml` or `action.yaml` in the directory of an action
+func LoadAction(dir string) (*Action, error) {
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		src, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := ParseAction(src)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse action %s: %v", filepath.Join(dir, name), err)
+		}
+
+		return a, nil
+	}
+
+	return nil, fmt.Errorf("no action.yml found in %s", dir)
+}
+
+// stepWith evaluates the `with:` values of a step running an action at the given workflow key of
+// the steps, see jobStepsKey. Names are in lower case.
+func stepWith(step *actionlint.Step, ctx expr.ContextData, key string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	e, ok := step.Exec.(*actionlint.ExecAction)
+	if !ok {
+		return values, nil
+	}
+
+	for name, in := range e.Inputs {
+		var v interface{} = ""
+		if in.Value != nil {
+			var err error
+			if v, err = evaluateString(in.Value.Value, ctx, key+".with"); err != nil {
+				return nil, fmt.Errorf("input %q: %v", name, err)
+			}
+		}
+
+		values[strings.ToLower(name)] = v
+	}
+
+	return values, nil
+}
+
+// ActionInputs returns the `inputs` context of an action run with the given `with:` values.
+// Inputs without a value use their default, evaluated with ctx. Like on GitHub, values are strings
+// and missing required inputs are not an error. Unknown values stay unknown.
+func ActionInputs(a *Action, with map[string]interface{}, ctx expr.ContextData) (expr.ContextData, error) {
+	inputs := expr.ContextData{}
+
+	for name, v := range with {
+		inputs[strings.ToLower(name)] = v
+	}
+
+	for _, name := range sortedKeys(a.Inputs) {
+		if _, ok := inputs[name]; ok {
+			continue
+		}
+
+		var v interface{} = ""
+		if d := a.Inputs[name].Default; d != nil {
+			var err error
+			if v, err = evaluateString(*d, ctx, "inputs.<inputs_id>.default"); err != nil {
+				return nil, fmt.Errorf("default of input %q: %v", name, err)
+			}
+		}
+		inputs[name] = v
+	}
+
+	for name, v := range inputs {
+		if v == expr.Unknown {
+			continue
+		}
+
+		r := expr.NewEvaluationResult(v)
+		if !r.Primitive() {
+			return nil, fmt.Errorf("input %q must be a string, got %s", name, r.Type.String())
+		}
+		inputs[name] = r.CoerceString()
+	}
+
+	return inputs, nil
+}
+
+// ActionOutputs evaluates the `outputs:` of a composite action when its last step completes. Ctx
+// contains the `steps` context of the composite action. Unknown values stay unknown.
+func ActionOutputs(a *Action, ctx expr.ContextData) (expr.ContextData, error) {
+	outputs := expr.ContextData{}
+
+	for _, name := range sortedKeys(a.Outputs) {
+		v, err := evaluateString(a.Outputs[name].Value, ctx, "outputs.<output_id>.value")
+		if err != nil {
+			return nil, fmt.Errorf("output %q: %v", name, err)
+		}
+
+		if v == expr.Unknown {
+			outputs[name] = expr.Unknown
+			continue
+		}
+
+		r := expr.NewEvaluationResult(v)
+		if !r.Primitive() {
+			return nil, fmt.Errorf("output %q must evaluate to a string, got %s", name, r.Type.String())
+		}
+		outputs[name] = r.CoerceString()
+	}
+
+	return outputs, nil
+}
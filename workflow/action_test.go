@@ -0,0 +1,63 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAction(t *testing.T) {
+	a, err := ParseAction([]byte(`
+name: Setup
+inputs:
+  Node-Version:
+    description: node version
+    default: 20
+  token:
+    description: token
+    required: true
+outputs:
+  cache-hit:
+    description: cache hit
+    value: ${{ steps.cache.outputs.cache-hit }}
+runs:
+  using: composite
+  steps:
+    - id: cache
+      uses: actions/cache@v4
+      with:
+        path: node_modules
+    - run: npm ci
+      shell: bash
+      if: steps.cache.outputs.cache-hit != 'true'
+`))
+	if err != nil {
+		t.Fatalf("ParseAction() error = %v", err)
+	}
+
+	if a.Name != "Setup" || a.Runs.Using != "composite" || len(a.Runs.Steps) != 2 {
+		t.Fatalf("ParseAction() = %+v, want composite action Setup with 2 steps", a)
+	}
+
+	if d := a.Inputs["node-version"].Default; d == nil || *d != "20" {
+		t.Errorf("ParseAction() default of node-version = %v, want 20", d)
+	}
+	if !a.Inputs["token"].Required {
+		t.Errorf("ParseAction() token is not required")
+	}
+	if got := a.Outputs["cache-hit"].Value; got != "${{ steps.cache.outputs.cache-hit }}" {
+		t.Errorf("ParseAction() value of cache-hit = %q", got)
+	}
+	if got := StepIDs(a.Runs.Steps); !reflect.DeepEqual(got, []string{"cache", "__run"}) {
+		t.Errorf("ParseAction() step IDs = %v", got)
+	}
+
+	for _, src := range []string{
+		"name: x\n",
+		"runs:\n  using: composite\n",
+		"runs:\n  using: composite\n  steps:\n    - foo: bar\n",
+	} {
+		if _, err := ParseAction([]byte(src)); err == nil {
+			t.Errorf("ParseAction(%q) error = nil, want error", src)
+		}
+	}
+}
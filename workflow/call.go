@@ -0,0 +1,281 @@
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// MaxCallDepth is the maximum number of levels of workflows connected by reusable workflow calls,
+// including the top-level caller
+const MaxCallDepth = 10
+
+// localPath returns the path of a workflow or action referenced by a local `uses:` like
+// `./.github/workflows/deploy.yml` relative to the repository, or false for remote ones
+func localPath(uses string) (string, bool) {
+	if !strings.HasPrefix(uses, "./") || strings.Contains(uses, "${{") {
+		return "", false
+	}
+
+	return filepath.FromSlash(uses), true
+}
+
+// CallWith evaluates the `with:` values the job passes to the reusable workflow it calls. Names
+// are in lower case.
+func CallWith(job *actionlint.Job, ctx expr.ContextData) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+
+	for name, in := range job.WorkflowCall.Inputs {
+		var v interface{} = ""
+		if in.Value != nil {
+			var err error
+			if v, err = evaluateString(in.Value.Value, ctx, "jobs.<job_id>.with.<with_id>"); err != nil {
+				return nil, fmt.Errorf("input %q: %v", name, err)
+			}
+		}
+
+		values[strings.ToLower(name)] = v
+	}
+
+	return values, nil
+}
+
+// CallSecrets returns the `secrets` context of the reusable workflow called by the job. Secrets
+// are passed by `secrets:` or all secrets of the caller with `secrets: inherit`, see
+// ParseWorkflow. Secrets not declared by the called workflow and missing required secrets are an
+// error. `secrets.GITHUB_TOKEN` is always passed.
+func CallSecrets(job *actionlint.Job, callee *actionlint.Workflow, ctx expr.ContextData) (interface{}, error) {
+	secrets, _ := ctx["secrets"].(expr.ContextData)
+
+	if _, ok := job.WorkflowCall.Secrets[inheritSecrets]; ok {
+		if secrets == nil {
+			return expr.Unknown, nil
+		}
+		return secrets, nil
+	}
+
+	declared := map[string]*actionlint.WorkflowCallEventSecret{}
+	if call := callEvent(callee); call != nil {
+		for name, s := range call.Secrets {
+			declared[strings.ToLower(name.Value)] = s
+		}
+	}
+
+	passed := expr.ContextData{}
+	if secrets == nil {
+		passed["github_token"] = expr.Unknown
+	} else if token, ok := secrets["github_token"]; ok {
+		passed["github_token"] = token
+	}
+
+	for name, s := range job.WorkflowCall.Secrets {
+		name = strings.ToLower(name)
+		if _, ok := declared[name]; !ok {
+			return nil, fmt.Errorf("secret %q is not defined by the called workflow", name)
+		}
+
+		var v interface{} = ""
+		if s.Value != nil {
+			var err error
+			if v, err = evaluateString(s.Value.Value, ctx, "jobs.<job_id>.secrets.<secrets_id>"); err != nil {
+				return nil, fmt.Errorf("secret %q: %v", name, err)
+			}
+		}
+		passed[name] = v
+	}
+
+	for _, name := range sortedKeys(declared) {
+		if _, ok := passed[name]; !ok && declared[name].Required != nil && declared[name].Required.Value {
+			return nil, fmt.Errorf("secret %q is required by the called workflow", name)
+		}
+	}
+
+	return passed, nil
+}
+
+// CallOutputs evaluates the `on.workflow_call.outputs` of a called workflow when it completes.
+// Ctx contains the `jobs` context with the results and outputs of the jobs of the called
+// workflow. Like job outputs, values are strings, outputs derived from secrets are dropped, and
+// unknown values stay unknown.
+func CallOutputs(w *actionlint.Workflow, ctx expr.ContextData) (expr.ContextData, error) {
+	call := callEvent(w)
+	if call == nil {
+		return nil, fmt.Errorf("workflow is not triggered by workflow_call events")
+	}
+
+	declared := map[string]*actionlint.WorkflowCallEventOutput{}
+	for name, o := range call.Outputs {
+		declared[strings.ToLower(name.Value)] = o
+	}
+
+	outputs := expr.ContextData{}
+	for _, name := range sortedKeys(declared) {
+		o := declared[name]
+		if o.Value == nil {
+			outputs[name] = ""
+			continue
+		}
+
+		v, sensitive, err := evaluateTemplate(o.Value.Value, ctx, "on.workflow_call.outputs.<output_id>.value")
+		if err != nil {
+			return nil, fmt.Errorf("output %q: %v", name, err)
+		}
+
+		if sensitive {
+			continue
+		}
+
+		if v == expr.Unknown {
+			outputs[name] = expr.Unknown
+			continue
+		}
+
+		r := expr.NewEvaluationResult(v)
+		if !r.Primitive() {
+			return nil, fmt.Errorf("output %q must evaluate to a string, got %s", name, r.Type.String())
+		}
+
+		outputs[name] = r.CoerceString()
+	}
+
+	return outputs, nil
+}
+
+// JobsContext returns the `jobs` context of a reusable workflow from the results of its jobs
+func JobsContext(results map[string]*JobResult) expr.ContextData {
+	jobs := expr.ContextData{}
+
+	for id, r := range results {
+		outputs := r.Outputs
+		if outputs == nil {
+			outputs = expr.ContextData{}
+		}
+		jobs[id] = expr.ContextData{"result": r.Result, "outputs": outputs}
+	}
+
+	return jobs
+}
+
+// prefixJobNames names the jobs of a called workflow, and of the workflows they call in turn, after
+// the run of the caller, see CalledJobDisplayName
+func prefixJobNames(plan *Plan, caller string) {
+	for _, jp := range plan.Jobs {
+		jp.Name = CalledJobDisplayName(caller, jp.Name)
+
+		for _, r := range jp.Runs {
+			r.Name = CalledJobDisplayName(caller, r.Name)
+			if r.Workflow != nil {
+				prefixJobNames(r.Workflow, caller)
+			}
+		}
+	}
+}
+
+// workflowResult returns the result of a called workflow from the results of its jobs, and the
+// reason for a failure. Skipped jobs don't fail the workflow.
+func workflowResult(plan *Plan) (interface{}, string) {
+	var result interface{} = JobSuccess
+	reason := ""
+
+	ids := make([]string, 0, len(plan.Jobs))
+	results := map[string]interface{}{}
+	for _, jp := range plan.Jobs {
+		ids = append(ids, jp.ID)
+		results[jp.ID] = jp.Result.Result
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		switch r := results[id]; {
+		case r == JobFailure:
+			if result != JobFailure {
+				result, reason = JobFailure, fmt.Sprintf("job %q of the called workflow fails", id)
+			}
+		case r == JobCancelled && result != JobFailure:
+			result = JobCancelled
+		case r != JobSuccess && r != JobSkipped && result == JobSuccess:
+			result = expr.Unknown
+		}
+	}
+
+	return result, reason
+}
+
+// simulateCall simulates a single run of a job calling a local reusable workflow. The jobs of the
+// called workflow are simulated with the `github` context of the caller.
+func (s *simulator) simulateCall(job *actionlint.Job, name string, matrix expr.ContextData, ctx expr.ContextData, path string) *JobRunPlan {
+	run := &JobRunPlan{Name: name, Matrix: matrix, Outputs: expr.Unknown}
+
+	fail := func(err error) *JobRunPlan {
+		run.Result, run.Reason = JobFailure, err.Error()
+		return run
+	}
+
+	if s.depth+1 >= MaxCallDepth {
+		return fail(fmt.Errorf("reusable workflows can't be nested more than %d levels", MaxCallDepth))
+	}
+
+	callee, err := LoadWorkflow(filepath.Join(s.opts.Dir, path))
+	if err != nil {
+		return fail(err)
+	}
+
+	with, err := CallWith(job, ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	secrets, err := CallSecrets(job, callee, ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	github, vars := ctx["github"], ctx["vars"]
+
+	inputs, err := CallInputs(callee, with, expr.ContextData{"github": github, "vars": vars})
+	if err != nil {
+		return fail(err)
+	}
+
+	opts := s.opts
+	opts.Contexts = copyContext(s.opts.Contexts)
+	delete(opts.Contexts, "inputs")
+	opts.Contexts["github"] = github
+	opts.Contexts["vars"] = vars
+	opts.Contexts["secrets"] = secrets
+
+	nested := &simulator{
+		workflow: callee,
+		event:    s.event,
+		opts:     opts,
+		results:  map[string]*JobResult{},
+		inputs:   inputs,
+		depth:    s.depth + 1,
+	}
+
+	plan, err := nested.simulate()
+	if err != nil {
+		return fail(err)
+	}
+	prefixJobNames(plan, name)
+	run.Workflow = plan
+
+	run.Result, run.Reason = workflowResult(plan)
+
+	outputs, err := CallOutputs(callee, expr.ContextData{
+		"github": github,
+		"vars":   vars,
+		"inputs": inputs,
+		"jobs":   JobsContext(nested.results),
+	})
+	if err != nil {
+		return fail(err)
+	}
+	run.Outputs = outputs
+
+	return run
+}
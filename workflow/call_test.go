@@ -0,0 +1,239 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// testRepo writes the workflows to `.github/workflows` of a temporary directory
+func testRepo(t *testing.T, workflows map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	wd := filepath.Join(dir, ".github", "workflows")
+	if err := os.MkdirAll(wd, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, src := range workflows {
+		if err := os.WriteFile(filepath.Join(wd, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestParseWorkflow_InheritSecrets(t *testing.T) {
+	w, err := ParseWorkflow([]byte(`
+on: push
+jobs:
+  build:
+    uses: ./.github/workflows/build.yml
+    secrets: inherit
+  deploy:
+    uses: ./.github/workflows/deploy.yml
+    secrets:
+      token: ${{ secrets.TOKEN }}
+`))
+	if err != nil {
+		t.Fatalf("ParseWorkflow() error = %v", err)
+	}
+
+	if _, ok := w.Jobs["build"].WorkflowCall.Secrets[inheritSecrets]; !ok {
+		t.Errorf("ParseWorkflow() build doesn't inherit secrets")
+	}
+	if _, ok := w.Jobs["deploy"].WorkflowCall.Secrets[inheritSecrets]; ok {
+		t.Errorf("ParseWorkflow() deploy inherits secrets")
+	}
+}
+
+func TestParseWorkflow_NonASCII(t *testing.T) {
+	w, err := ParseWorkflow([]byte(`
+on:
+  workflow_dispatch:
+    inputs:
+      niveau: { description: "Niveau de détail 🔍", type: number }
+jobs:
+  build:
+    name: Générer 🚀
+    uses: ./.github/workflows/build.yml
+    secrets: inherit
+  deploy: { name: Déploiement 🚀, uses: ./.github/workflows/deploy.yml, secrets: inherit }
+  release: { secrets: inherit, name: Publication ✨, uses: ./.github/workflows/release.yml }
+  notify: { name: Benachrichtigung über 🔔, uses: ./.github/workflows/notify.yml }
+`))
+	if err != nil {
+		t.Fatalf("ParseWorkflow() error = %v", err)
+	}
+
+	for id, want := range map[string]bool{"build": true, "deploy": true, "release": true, "notify": false} {
+		if _, ok := w.Jobs[id].WorkflowCall.Secrets[inheritSecrets]; ok != want {
+			t.Errorf("ParseWorkflow() %s inherits secrets = %v, want %v", id, ok, want)
+		}
+	}
+
+	var dispatch *actionlint.WorkflowDispatchEvent
+	for _, e := range w.On {
+		if d, ok := e.(*actionlint.WorkflowDispatchEvent); ok {
+			dispatch = d
+		}
+	}
+	if dispatch == nil || dispatch.Inputs["niveau"] == nil || dispatch.Inputs["niveau"].Type != dispatchInputTypeNumber {
+		t.Errorf("ParseWorkflow() niveau isn't a number input")
+	}
+	if got, want := dispatch.Inputs["niveau"].Description.Value, "Niveau de détail 🔍"; got != want {
+		t.Errorf("ParseWorkflow() niveau description = %q, want %q", got, want)
+	}
+}
+
+func TestSimulate_ReusableWorkflow(t *testing.T) {
+	dir := testRepo(t, map[string]string{
+		"ci.yml": `
+on: push
+jobs:
+  deploy:
+    uses: ./.github/workflows/deploy.yml
+    with:
+      environment: ${{ github.ref == 'refs/heads/main' && 'production' || 'staging' }}
+      dry-run: ${{ github.ref != 'refs/heads/main' }}
+    secrets: inherit
+  notify:
+    needs: deploy
+    if: needs.deploy.outputs.url == 'https://production.example.com'
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`,
+		"deploy.yml": `
+on:
+  workflow_call:
+    inputs:
+      environment:
+        description: environment
+        type: string
+        required: true
+      dry-run:
+        description: dry run
+        type: boolean
+    secrets:
+      token:
+        description: token
+    outputs:
+      url:
+        description: url
+        value: ${{ jobs.deploy.outputs.url }}
+jobs:
+  deploy:
+    if: ${{ !inputs.dry-run }}
+    runs-on: ubuntu-latest
+    outputs:
+      url: https://${{ inputs.environment }}.example.com
+    steps:
+      - if: github.event_name == 'push' && env.TOKEN != ''
+        run: ./deploy.sh
+        env:
+          TOKEN: ${{ secrets.token }}
+`,
+	})
+
+	w, err := LoadWorkflow(filepath.Join(dir, ".github", "workflows", "ci.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := expr.ContextData{
+		"github":  expr.ContextData{"ref": "refs/heads/main"},
+		"secrets": expr.ContextData{"token": "secret"},
+	}
+
+	plan, err := Simulate(w, &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/main"}}, Options{Contexts: ctx, Dir: dir})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	deploy := plan.Job("deploy")
+	r := deploy.Runs[0]
+	if r.Workflow == nil || r.Result != JobSuccess {
+		t.Fatalf("Simulate() deploy = %v (%s), want success of called workflow\n%s", r.Result, r.Reason, r.Workflow)
+	}
+
+	called := r.Workflow.Job("deploy")
+	if called.Status != StatusRun || called.Runs[0].Steps[0].Status != StatusRun {
+		t.Errorf("Simulate() called workflow =\n%s", r.Workflow)
+	}
+
+	want := expr.ContextData{"url": "https://production.example.com"}
+	if !reflect.DeepEqual(deploy.Result.Outputs, want) {
+		t.Errorf("Simulate() deploy outputs = %v, want %v", deploy.Result.Outputs, want)
+	}
+
+	if got := plan.Job("notify").Status; got != StatusRun {
+		t.Errorf("Simulate() notify = %v, want run", got)
+	}
+}
+
+func TestSimulate_ReusableWorkflowErrors(t *testing.T) {
+	dir := testRepo(t, map[string]string{
+		"ci.yml": `
+on: push
+jobs:
+  missing:
+    uses: ./.github/workflows/missing.yml
+  input:
+    uses: ./.github/workflows/called.yml
+    with:
+      other: x
+  secret:
+    uses: ./.github/workflows/called.yml
+    secrets:
+      other: x
+  recursive:
+    uses: ./.github/workflows/recursive.yml
+`,
+		"called.yml": `
+on: workflow_call
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`,
+		"recursive.yml": `
+on: workflow_call
+jobs:
+  call:
+    uses: ./.github/workflows/recursive.yml
+`,
+	})
+
+	w, err := LoadWorkflow(filepath.Join(dir, ".github", "workflows", "ci.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := Simulate(w, &Event{Name: "push", Payload: expr.ContextData{}}, Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	for _, id := range []string{"missing", "input", "secret", "recursive"} {
+		if got := plan.Job(id).Result.Result; got != JobFailure {
+			t.Errorf("Simulate() %s = %v, want failure", id, got)
+		}
+	}
+
+	// The call at the maximum depth fails, failing every caller
+	depth := 0
+	for p := plan.Job("recursive").Runs[0].Workflow; p != nil; p = p.Jobs[0].Runs[0].Workflow {
+		depth++
+	}
+	if depth != MaxCallDepth-1 {
+		t.Errorf("Simulate() nested workflows = %d, want %d", depth, MaxCallDepth-1)
+	}
+}
@@ -0,0 +1,240 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// Catalog is an offline catalog of the metadata of remote actions, used instead of fetching their
+// `action.yml` from GitHub. The metadata of `owner/repo@ref` is read from
+// `<dir>/owner/repo@ref/action.yml`, and of actions in a subdirectory like `owner/repo/path@ref`
+// from `<dir>/owner/repo@ref/path/action.yml`. A directory can hold just the `action.yml` files
+// or a vendored checkout of the repository at the ref.
+type Catalog struct {
+	Dir string
+
+	mu sync.Mutex
+
+	// actions caches the actions looked up so far by reference, nil for actions not in the
+	// catalog
+	actions map[string]*Action
+}
+
+// NewCatalog returns the catalog in the directory
+func NewCatalog(dir string) *Catalog {
+	return &Catalog{Dir: dir, actions: map[string]*Action{}}
+}
+
+// Lookup returns the metadata of a remote action referenced by `uses:`, like
+// `actions/checkout@v4`. It returns nil for actions not in the catalog, local actions, and Docker
+// images.
+func (c *Catalog) Lookup(uses string) (*Action, error) {
+	dir, ok := c.actionDir(uses)
+	if !ok {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.actions == nil {
+		c.actions = map[string]*Action{}
+	}
+	if a, ok := c.actions[uses]; ok {
+		return a, nil
+	}
+
+	var a *Action
+	if _, err := os.Stat(dir); err == nil {
+		if a, err = LoadAction(dir); err != nil {
+			return nil, fmt.Errorf("metadata of %s: %v", uses, err)
+		}
+	}
+	c.actions[uses] = a
+
+	return a, nil
+}
+
+// actionDir returns the directory holding the metadata of a remote action
+func (c *Catalog) actionDir(uses string) (string, bool) {
+	if strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") || strings.Contains(uses, "${{") {
+		return "", false
+	}
+
+	name, ref, ok := strings.Cut(uses, "@")
+	if !ok || ref == "" {
+		return "", false
+	}
+
+	parts := strings.SplitN(name, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+
+	dir := filepath.Join(c.Dir, parts[0], parts[1]+"@"+ref)
+	if len(parts) == 3 {
+		dir = filepath.Join(dir, filepath.FromSlash(parts[2]))
+	}
+
+	// References like `a/b@../..` must not escape the catalog
+	rel, err := filepath.Rel(c.Dir, dir)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return dir, true
+}
+
+// CheckWith returns warnings for the `with:` values of a step running the action: inputs the
+// action doesn't declare, and required inputs without a value or default. Like the runner, these
+// are not errors.
+func CheckWith(a *Action, step *actionlint.Step) []string {
+	warnings := []string{}
+
+	e, ok := step.Exec.(*actionlint.ExecAction)
+	if !ok {
+		return warnings
+	}
+
+	given := map[string]bool{}
+	for name := range e.Inputs {
+		given[strings.ToLower(name)] = true
+	}
+
+	for _, name := range sortedKeys(given) {
+		if _, ok := a.Inputs[name]; !ok {
+			warnings = append(warnings, fmt.Sprintf("input %q is not declared by %s", name, e.Uses.Value))
+		}
+	}
+
+	for _, name := range sortedKeys(a.Inputs) {
+		if in := a.Inputs[name]; in.Required && in.Default == nil && !given[name] {
+			warnings = append(warnings, fmt.Sprintf("required input %q of %s is missing", name, e.Uses.Value))
+		}
+	}
+
+	return warnings
+}
+
+// InputEnv returns the `INPUT_*` environment variables the runner passes to an action for its
+// inputs, e.g. `INPUT_NODE-VERSION` for `node-version`
+func InputEnv(inputs expr.ContextData) expr.ContextData {
+	env := expr.ContextData{}
+	for name, v := range inputs {
+		env["INPUT_"+strings.ToUpper(strings.ReplaceAll(name, " ", "_"))] = v
+	}
+
+	return env
+}
+
+// CheckOutputReferences returns warnings for the references to `steps.<id>.outputs.<name>` in
+// the expressions of the step where the action run by step `<id>` is known and doesn't declare
+// the output. Actions are the actions run by earlier steps by step ID. The outputs of actions
+// that aren't simulated stay unknown though, since actions can set outputs they don't declare.
+func CheckOutputReferences(step *actionlint.Step, actions map[string]*Action) []string {
+	warnings := []string{}
+	seen := map[string]bool{}
+
+	for _, e := range stepExpressions(step) {
+		actionlint.VisitExprNode(e, func(n, _ actionlint.ExprNode, entering bool) {
+			if !entering {
+				return
+			}
+
+			path, ok := propertyPath(n)
+			if !ok || len(path) != 4 || !strings.EqualFold(path[0], "steps") || !strings.EqualFold(path[2], "outputs") {
+				return
+			}
+			id, name := strings.ToLower(path[1]), strings.ToLower(path[3])
+
+			a, ok := actions[id]
+			if !ok || a == nil || seen[id+"."+name] {
+				return
+			}
+			seen[id+"."+name] = true
+
+			if _, ok := a.Outputs[name]; !ok {
+				warnings = append(warnings, fmt.Sprintf("output %q of step %q is not declared by its action", name, id))
+			}
+		})
+	}
+
+	return warnings
+}
+
+// propertyPath returns the names of the properties accessed by the node, starting with the
+// context, like `steps`, `build`, `outputs`, `version` for `steps.build.outputs['version']`. Ok
+// is false unless the node only accesses properties by literal names.
+func propertyPath(n actionlint.ExprNode) (path []string, ok bool) {
+	switch tn := n.(type) {
+	case *actionlint.VariableNode:
+		return []string{tn.Name}, true
+
+	case *actionlint.ObjectDerefNode:
+		if path, ok = propertyPath(tn.Receiver); ok {
+			return append(path, tn.Property), true
+		}
+
+	case *actionlint.IndexAccessNode:
+		if idx, isString := tn.Index.(*actionlint.StringNode); isString {
+			if path, ok = propertyPath(tn.Operand); ok {
+				return append(path, idx.Value), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// stepExpressions returns the expressions of a step. Expressions that fail to parse are left out,
+// actionlint reports them.
+func stepExpressions(step *actionlint.Step) []actionlint.ExprNode {
+	nodes := []actionlint.ExprNode{}
+	add := func(s *actionlint.String) {
+		if s == nil {
+			return
+		}
+
+		exprs, err := expr.ParseTemplate(s.Value)
+		if err != nil {
+			return
+		}
+		for _, e := range exprs {
+			nodes = append(nodes, e.Node)
+		}
+	}
+
+	// Conditions don't need to be enclosed in ${{ }}
+	if step.If != nil {
+		if n, ok, err := parseExpression(step.If.Value); ok && err == nil {
+			nodes = append(nodes, n)
+		} else {
+			add(step.If)
+		}
+	}
+	add(step.Name)
+
+	if step.Env != nil {
+		add(step.Env.Expression)
+		for _, name := range sortedKeys(step.Env.Vars) {
+			add(step.Env.Vars[name].Value)
+		}
+	}
+
+	switch e := step.Exec.(type) {
+	case *actionlint.ExecRun:
+		add(e.Run)
+	case *actionlint.ExecAction:
+		for _, name := range sortedKeys(e.Inputs) {
+			add(e.Inputs[name].Value)
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,291 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+// testCatalog writes the action metadata to a catalog in a temporary directory, by path like
+// `actions/checkout@v4`
+func testCatalog(t *testing.T, actions map[string]string) *Catalog {
+	t.Helper()
+
+	dir := t.TempDir()
+	for path, src := range actions {
+		ad := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(ad, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(ad, "action.yml"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return NewCatalog(dir)
+}
+
+const checkoutAction = `
+name: Checkout
+inputs:
+  fetch-depth:
+    description: depth
+    default: 1
+  token:
+    description: token
+    default: ${{ github.token }}
+outputs:
+  ref:
+    description: ref
+  commit:
+    description: commit
+runs:
+  using: node20
+  main: dist/index.js
+`
+
+func TestCatalog_Lookup(t *testing.T) {
+	c := testCatalog(t, map[string]string{
+		"actions/checkout@v4":          checkoutAction,
+		"github/codeql-action@v3/init": "name: Init\nruns:\n  using: node20\n  main: init.js\n",
+		"owner/broken@v1":              "runs: [",
+	})
+
+	tests := []struct {
+		uses    string
+		want    string
+		wantErr bool
+	}{
+		{uses: "actions/checkout@v4", want: "Checkout"},
+		{uses: "github/codeql-action/init@v3", want: "Init"},
+		{uses: "actions/checkout@v3"},
+		{uses: "actions/checkout"},
+		{uses: "./.github/actions/setup"},
+		{uses: "docker://alpine:3"},
+		{uses: "actions/checkout@../../../etc"},
+		{uses: "owner/broken@v1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			a, err := c.Lookup(tt.uses)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got := ""
+			if a != nil {
+				got = a.Name
+			}
+			if got != tt.want {
+				t.Errorf("Lookup() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_LookupLiteral(t *testing.T) {
+	dir := testCatalog(t, map[string]string{"actions/checkout@v4": checkoutAction}).Dir
+
+	c := &Catalog{Dir: dir}
+	a, err := c.Lookup("actions/checkout@v4")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if a == nil || a.Name != "Checkout" {
+		t.Errorf("Lookup() = %v, want Checkout", a)
+	}
+}
+
+func TestCatalog_LookupRelativeDir(t *testing.T) {
+	c := testCatalog(t, map[string]string{"actions/checkout@v4": checkoutAction})
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(c.Dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	for _, dir := range []string{".", "./", "../" + filepath.Base(c.Dir)} {
+		a, err := NewCatalog(dir).Lookup("actions/checkout@v4")
+		if err != nil {
+			t.Fatalf("Lookup() error = %v", err)
+		}
+		if a == nil || a.Name != "Checkout" {
+			t.Errorf("Lookup() in %q = %v, want Checkout", dir, a)
+		}
+
+		if a, _ := NewCatalog(dir).Lookup("actions/checkout@../../.."); a != nil {
+			t.Errorf("Lookup() in %q escapes the catalog", dir)
+		}
+	}
+}
+
+func TestCheckOutputReferences(t *testing.T) {
+	tests := []struct {
+		name string
+		step string
+		want []string
+	}{
+		{
+			name: "property access",
+			step: `
+      - run: echo ${{ steps.checkout.outputs.commit }} ${{ steps.checkout.outputs.sha }}`,
+			want: []string{`output "sha" of step "checkout" is not declared by its action`},
+		},
+		{
+			name: "index access",
+			step: `
+      - run: echo ${{ steps['checkout'].outputs.sha }} ${{ steps.checkout.outputs['tag'] }}`,
+			want: []string{
+				`output "sha" of step "checkout" is not declared by its action`,
+				`output "tag" of step "checkout" is not declared by its action`,
+			},
+		},
+		{
+			name: "string literal",
+			step: `
+      - run: echo ${{ format('steps.checkout.outputs.{0}', 'sha') }}`,
+			want: []string{},
+		},
+		{
+			name: "multiline expression",
+			step: `
+      - run: |
+          echo ${{
+            steps.checkout.outputs.sha
+          }}`,
+			want: []string{`output "sha" of step "checkout" is not declared by its action`},
+		},
+		{
+			name: "condition in expression",
+			step: `
+      - if: ${{ steps.checkout.outputs.sha != '' }}
+        run: echo`,
+			want: []string{`output "sha" of step "checkout" is not declared by its action`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:`+tt.step+`
+`)
+			actions := map[string]*Action{
+				"checkout": {Outputs: map[string]*ActionOutput{"ref": {}, "commit": {}}},
+			}
+
+			got := CheckOutputReferences(w.Jobs["build"].Steps[0], actions)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckOutputReferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulate_Catalog(t *testing.T) {
+	c := testCatalog(t, map[string]string{"actions/checkout@v4": checkoutAction})
+
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - id: checkout
+        uses: actions/checkout@v4
+        env:
+          Git_Trace: 1
+        with:
+          fetch-depth: 0
+          depth: 1
+      - if: steps.checkout.outputs.sha != ''
+        run: echo ${{ steps.checkout.outputs.commit }}
+      - uses: actions/setup-node@v4
+`)
+
+	ctx := expr.ContextData{"github": expr.ContextData{"token": "***"}}
+
+	plan, err := Simulate(w, &Event{Name: "push", Payload: expr.ContextData{}}, Options{Contexts: ctx, Catalog: c})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := `build: run
+  [0] Run actions/checkout@v4: run
+    warning: input "depth" is not declared by actions/checkout@v4
+  [1] Run echo ${{ steps.checkout.outputs.commit }}: undecidable (condition ` + "`steps.checkout.outputs.sha != ''`" + ` depends on values only known at runtime)
+    warning: output "sha" of step "checkout" is not declared by its action
+  [2] Run actions/setup-node@v4: run
+`
+	if got := plan.String(); got != want {
+		t.Errorf("Simulate() =\n%s\nwant\n%s", got, want)
+	}
+
+	steps := plan.Jobs[0].Runs[0].Steps
+
+	inputs := expr.ContextData{"fetch-depth": "0", "depth": "1", "token": "***"}
+	if !reflect.DeepEqual(steps[0].Inputs, inputs) {
+		t.Errorf("Simulate() inputs = %v, want %v", steps[0].Inputs, inputs)
+	}
+	if steps[2].Inputs != nil {
+		t.Errorf("Simulate() inputs of action without metadata = %v, want nil", steps[2].Inputs)
+	}
+
+	// Variables of the step keep their names next to the INPUT_ variables
+	env := expr.ContextData{"Git_Trace": "1", "INPUT_FETCH-DEPTH": "0", "INPUT_DEPTH": "1", "INPUT_TOKEN": "***"}
+	if !reflect.DeepEqual(steps[0].Env, env) {
+		t.Errorf("Simulate() env = %v, want %v", steps[0].Env, env)
+	}
+	if !reflect.DeepEqual(steps[2].Env, expr.ContextData{}) {
+		t.Errorf("Simulate() env of action without metadata = %v, want empty", steps[2].Env)
+	}
+}
+
+func TestCheckWith(t *testing.T) {
+	a, err := ParseAction([]byte(`
+inputs:
+  token:
+    description: token
+    required: true
+  path:
+    description: path
+    required: true
+    default: .
+runs:
+  using: node20
+  main: index.js
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: owner/action@v1
+        with:
+          Extra: x
+`)
+
+	got := CheckWith(a, w.Jobs["build"].Steps[0])
+	want := []string{
+		`input "extra" is not declared by owner/action@v1`,
+		`required input "token" of owner/action@v1 is missing`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckWith() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package workflow
+
+import (
+	"fmt"
+	"path/filepath"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// MaxCompositeDepth is the maximum number of levels of composite actions running other
+// composite actions the runner allows
+const MaxCompositeDepth = 9
+
+// compositeRun is the simulated outcome of a step running a local composite action
+type compositeRun struct {
+	steps []*StepPlan
+
+	// status is the status after the last step of the action, reason explains a failure
+	status expr.Status
+	reason string
+
+	// outputs are the outputs of the action, or expr.Unknown
+	outputs interface{}
+
+	// githubEnv are the variables the steps of the action write to GITHUB_ENV
+	githubEnv interface{}
+}
+
+// action returns the metadata of the action run by the step: local actions are loaded from
+// Options.Dir, remote actions are looked up in Options.Catalog. It returns nil for `run:` steps
+// and actions whose metadata isn't available.
+func (s *simulator) action(step *actionlint.Step) (*Action, error) {
+	e, ok := step.Exec.(*actionlint.ExecAction)
+	if !ok || e.Uses == nil {
+		return nil, nil
+	}
+
+	if path, ok := localPath(e.Uses.Value); ok {
+		if s.opts.Dir == "" {
+			return nil, nil
+		}
+		return LoadAction(filepath.Join(s.opts.Dir, path))
+	}
+
+	if s.opts.Catalog == nil {
+		return nil, nil
+	}
+
+	return s.opts.Catalog.Lookup(e.Uses.Value)
+}
+
+// simulateComposite simulates a step running a composite action with the given inputs. Ctx are
+// the contexts of the step, status and reason the status before the step.
+func (s *simulator) simulateComposite(a *Action, inputs expr.ContextData, ctx expr.ContextData, status expr.Status, reason string, depth int) (*compositeRun, error) {
+	if depth+1 > MaxCompositeDepth {
+		return nil, fmt.Errorf("composite actions can't be nested more than %d levels", MaxCompositeDepth)
+	}
+
+	// The steps of the action see its inputs and their own steps only
+	cctx := copyContext(ctx)
+	cctx["inputs"] = inputs
+
+	plans, steps, githubEnv := s.runSteps(a.Runs.Steps, compositeStepsKey, cctx, status, reason, depth+1)
+
+	c := &compositeRun{steps: plans, githubEnv: githubEnv}
+	c.status, c.reason = steps.Status()
+
+	cctx["steps"] = steps.Context()
+	outputs, err := ActionOutputs(a, cctx)
+	if err != nil {
+		c.status, c.reason, c.outputs = expr.StatusFailure, err.Error(), expr.Unknown
+		return c, nil
+	}
+	c.outputs = outputs
+
+	return c, nil
+}
+
+// actionInputs returns the inputs of the action run by the step at the given key, see
+// ActionInputs
+func (s *simulator) actionInputs(a *Action, step *actionlint.Step, ctx expr.ContextData, key string) (expr.ContextData, error) {
+	with, err := stepWith(step, ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return ActionInputs(a, with, ctx)
+}
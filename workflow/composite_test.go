@@ -0,0 +1,196 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+// writeActions writes the actions to directories of `.github/actions` in the repository
+func writeActions(t *testing.T, dir string, actions map[string]string) {
+	t.Helper()
+
+	for name, src := range actions {
+		ad := filepath.Join(dir, ".github", "actions", name)
+		if err := os.MkdirAll(ad, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(ad, "action.yml"), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSimulate_CompositeAction(t *testing.T) {
+	dir := t.TempDir()
+	writeActions(t, dir, map[string]string{
+		"setup": `
+name: Setup
+inputs:
+  node-version:
+    description: node version
+    default: ${{ runner.os == 'Linux' && '20' || '18' }}
+  cache:
+    description: cache
+    default: "true"
+outputs:
+  version:
+    description: installed version
+    value: ${{ steps.install.outputs.version }}
+  cached:
+    description: cached
+    value: ${{ inputs.cache }}
+runs:
+  using: composite
+  steps:
+    - id: install
+      uses: ./.github/actions/install
+      with:
+        version: v${{ inputs.node-version }}
+    - if: inputs.cache == 'true'
+      run: echo "NODE_CACHE=$HOME/.npm" >> $GITHUB_ENV
+      shell: bash
+    - if: inputs.node-version == '18'
+      run: echo old
+      shell: bash
+`,
+		"install": `
+name: Install
+inputs:
+  version:
+    description: version
+outputs:
+  version:
+    description: installed version
+    value: ${{ inputs.version }}
+runs:
+  using: composite
+  steps:
+    - run: echo "VERSION=${{ inputs.version }}" >> $GITHUB_ENV
+      shell: bash
+`,
+	})
+
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - id: setup
+        uses: ./.github/actions/setup
+        with:
+          cache: false
+      - if: steps.setup.outputs.version == 'v20' && steps.setup.outputs.cached == 'false'
+        run: npm test
+      - if: env.VERSION == 'v20'
+        run: echo
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push", Payload: expr.ContextData{}}, Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := `build: run
+  [0] Run ./.github/actions/setup: run
+    [0] Run ./.github/actions/install: run
+      [0] Run echo "VERSION=${{ inputs.version }}" >> $GITHUB_ENV: run
+    [1] Run echo "NODE_CACHE=$HOME/.npm" >> $GITHUB_ENV: skipped (condition ` + "`inputs.cache == 'true'`" + ` is false)
+    [2] Run echo old: skipped (condition ` + "`inputs.node-version == '18'`" + ` is false)
+  [1] Run npm test: run
+  [2] Run echo: run
+`
+	if got := plan.String(); got != want {
+		t.Errorf("Simulate() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got, want := plan.Jobs[0].Runs[0].Steps[2].Env, (expr.ContextData{"VERSION": "v20"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Simulate() env of last step = %v, want %v", got, want)
+	}
+}
+
+func TestSimulate_CompositeActionErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeActions(t, dir, map[string]string{
+		"recursive": `
+name: Recursive
+runs:
+  using: composite
+  steps:
+    - uses: ./.github/actions/recursive
+`,
+		"secrets": `
+name: Secrets
+outputs:
+  token:
+    description: token
+    value: ${{ secrets.TOKEN }}
+runs:
+  using: composite
+  steps:
+    - run: echo
+      shell: bash
+`,
+		"node": `
+name: Node
+runs:
+  using: node20
+  main: index.js
+`,
+	})
+
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ./.github/actions/node
+      - uses: ./.github/actions/missing
+      - if: always()
+        uses: ./.github/actions/secrets
+        continue-on-error: true
+  recursive:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: ./.github/actions/recursive
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push", Payload: expr.ContextData{}}, Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	steps := plan.Job("build").Runs[0].Steps
+	if steps[0].Status != StatusRun || steps[0].Steps != nil {
+		t.Errorf("Simulate() node action = %v %v, want run without steps", steps[0].Status, steps[0].Steps)
+	}
+	if steps[1].Status != StatusError || !strings.Contains(steps[1].Reason, "no action.yml") {
+		t.Errorf("Simulate() missing action = %v (%s), want error", steps[1].Status, steps[1].Reason)
+	}
+	if !strings.Contains(steps[2].Reason, `context "secrets" is not available`) {
+		t.Errorf("Simulate() secrets action reason = %q, want unavailable context", steps[2].Reason)
+	}
+
+	if got := plan.Job("build").Result.Result; got != JobFailure {
+		t.Errorf("Simulate() build = %v, want failure", got)
+	}
+
+	// The composite action at the maximum depth fails, failing every composite action running it
+	depth := 0
+	for s := plan.Job("recursive").Runs[0].Steps[0]; len(s.Steps) > 0; s = s.Steps[0] {
+		depth++
+	}
+	if depth != MaxCompositeDepth {
+		t.Errorf("Simulate() nested composite actions = %d, want %d", depth, MaxCompositeDepth)
+	}
+
+	if got := plan.Job("recursive").Result.Result; got != JobFailure {
+		t.Errorf("Simulate() recursive = %v, want failure", got)
+	}
+}
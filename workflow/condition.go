@@ -0,0 +1,105 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// decision is the outcome of evaluating an `if:` condition
+type decision struct {
+	status Status
+	reason string
+}
+
+// parseExpression parses a single expression, which may or may not be wrapped in `${{ }}` like
+// in `if:` conditions. Ok is false if the string contains text around embedded expressions.
+func parseExpression(s string) (n actionlint.ExprNode, ok bool, err error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "${{") {
+		exprs, err := expr.ParseTemplate(s)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(exprs) != 1 || exprs[0].End != len(s) {
+			return nil, false, nil
+		}
+
+		return exprs[0].Node, true, nil
+	}
+
+	if strings.Contains(s, "${{") {
+		return nil, false, nil
+	}
+
+	lexer := actionlint.NewExprLexer(s + "}}")
+	parser := actionlint.NewExprParser()
+	n, perr := parser.Parse(lexer)
+	if perr != nil {
+		return nil, false, perr
+	}
+
+	return n, true, nil
+}
+
+// evaluateCondition decides whether a job or step with the given condition runs. Location is the
+// workflow key of the condition, see expr.LookupAvailability. Status is the status checked by the
+// status functions, statusReason explains a status other than success. Conditions without a
+// status function are implicitly combined with `success()`. Conditions that can't be parsed or
+// evaluated fail like on GitHub.
+func evaluateCondition(cond *actionlint.String, ctx expr.ContextData, location string, status expr.Status, statusReason string) decision {
+	if cond == nil || strings.TrimSpace(cond.Value) == "" {
+		return implicitSuccess(status, statusReason)
+	}
+
+	src := strings.TrimSpace(cond.Value)
+
+	n, ok, err := parseExpression(src)
+	if err != nil {
+		return decision{StatusError, fmt.Sprintf("could not parse condition `%s`: %v", src, err)}
+	}
+	if !ok {
+		// Text around expressions always results in a non-empty string
+		return decision{StatusRun, fmt.Sprintf("condition `%s` is not a single expression and always true", src)}
+	}
+
+	implicit := !expr.UsesStatusFunction(n)
+	if implicit && status != expr.StatusSuccess && status != expr.StatusUnknown {
+		return implicitSuccess(status, statusReason)
+	}
+
+	r, err := expr.EvaluateWithOptions(n, ctx, expr.EvaluateOptions{Status: status, Location: location})
+	if err != nil {
+		return decision{StatusError, fmt.Sprintf("could not evaluate condition `%s`: %v", src, err)}
+	}
+
+	if !r.TruthinessKnown() {
+		return decision{StatusUndecidable, fmt.Sprintf("condition `%s` depends on values only known at runtime", src)}
+	}
+
+	if r.Falsy() {
+		return decision{StatusSkipped, fmt.Sprintf("condition `%s` is false", src)}
+	}
+
+	if implicit && status == expr.StatusUnknown {
+		return decision{StatusUndecidable, statusReason}
+	}
+
+	return decision{StatusRun, ""}
+}
+
+// implicitSuccess decides a missing condition, which defaults to `success()`
+func implicitSuccess(status expr.Status, statusReason string) decision {
+	switch status {
+	case expr.StatusSuccess:
+		return decision{StatusRun, ""}
+	case expr.StatusUnknown:
+		return decision{StatusUndecidable, statusReason}
+	default:
+		return decision{StatusSkipped, statusReason}
+	}
+}
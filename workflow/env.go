@@ -0,0 +1,407 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultEnv maps the default environment variables set by the runner to the context and
+// property they are derived from
+var defaultEnv = []struct {
+	name     string
+	context  string
+	property string
+}{
+	{"GITHUB_ACTION", "github", "action"},
+	{"GITHUB_ACTION_PATH", "github", "action_path"},
+	{"GITHUB_ACTION_REPOSITORY", "github", "action_repository"},
+	{"GITHUB_ACTOR", "github", "actor"},
+	{"GITHUB_ACTOR_ID", "github", "actor_id"},
+	{"GITHUB_API_URL", "github", "api_url"},
+	{"GITHUB_BASE_REF", "github", "base_ref"},
+	{"GITHUB_ENV", "github", "env"},
+	{"GITHUB_EVENT_NAME", "github", "event_name"},
+	{"GITHUB_EVENT_PATH", "github", "event_path"},
+	{"GITHUB_GRAPHQL_URL", "github", "graphql_url"},
+	{"GITHUB_HEAD_REF", "github", "head_ref"},
+	{"GITHUB_JOB", "github", "job"},
+	{"GITHUB_OUTPUT", "github", "output"},
+	{"GITHUB_PATH", "github", "path"},
+	{"GITHUB_REF", "github", "ref"},
+	{"GITHUB_REF_NAME", "github", "ref_name"},
+	{"GITHUB_REF_PROTECTED", "github", "ref_protected"},
+	{"GITHUB_REF_TYPE", "github", "ref_type"},
+	{"GITHUB_REPOSITORY", "github", "repository"},
+	{"GITHUB_REPOSITORY_ID", "github", "repository_id"},
+	{"GITHUB_REPOSITORY_OWNER", "github", "repository_owner"},
+	{"GITHUB_REPOSITORY_OWNER_ID", "github", "repository_owner_id"},
+	{"GITHUB_RETENTION_DAYS", "github", "retention_days"},
+	{"GITHUB_RUN_ATTEMPT", "github", "run_attempt"},
+	{"GITHUB_RUN_ID", "github", "run_id"},
+	{"GITHUB_RUN_NUMBER", "github", "run_number"},
+	{"GITHUB_SERVER_URL", "github", "server_url"},
+	{"GITHUB_SHA", "github", "sha"},
+	{"GITHUB_STATE", "github", "state"},
+	{"GITHUB_STEP_SUMMARY", "github", "step_summary"},
+	{"GITHUB_TRIGGERING_ACTOR", "github", "triggering_actor"},
+	{"GITHUB_WORKFLOW", "github", "workflow"},
+	{"GITHUB_WORKFLOW_REF", "github", "workflow_ref"},
+	{"GITHUB_WORKFLOW_SHA", "github", "workflow_sha"},
+	{"GITHUB_WORKSPACE", "github", "workspace"},
+	{"RUNNER_ARCH", "runner", "arch"},
+	{"RUNNER_DEBUG", "runner", "debug"},
+	{"RUNNER_ENVIRONMENT", "runner", "environment"},
+	{"RUNNER_NAME", "runner", "name"},
+	{"RUNNER_OS", "runner", "os"},
+	{"RUNNER_TEMP", "runner", "temp"},
+	{"RUNNER_TOOL_CACHE", "runner", "tool_cache"},
+	{"RUNNER_WORKSPACE", "runner", "workspace"},
+}
+
+// DefaultEnv returns the default environment variables the runner sets for steps, like
+// GITHUB_REF or RUNNER_OS, derived from the `github` and `runner` contexts. Variables whose
+// property is missing from the contexts are left out, variables whose property or context is
+// unknown are unknown.
+//
+// The variables are set in the environment of the processes run by steps, they are not part of
+// the `env` context.
+func DefaultEnv(ctx expr.ContextData) expr.ContextData {
+	env := expr.ContextData{
+		"CI":             "true",
+		"GITHUB_ACTIONS": "true",
+	}
+
+	for _, e := range defaultEnv {
+		var v interface{}
+
+		switch c := ctx[e.context].(type) {
+		case expr.ContextData:
+			p, ok := c[e.property]
+			if !ok || p == nil {
+				continue
+			}
+			v = p
+		default:
+			if c != expr.Unknown {
+				continue
+			}
+			v = expr.Unknown
+		}
+
+		if v == expr.Unknown {
+			env[e.name] = expr.Unknown
+			continue
+		}
+
+		r := expr.NewEvaluationResult(v)
+		if !r.Primitive() {
+			continue
+		}
+		env[e.name] = r.CoerceString()
+	}
+
+	return env
+}
+
+// Environ returns the environment of the current process as a map, e.g. to pass to
+// ContextsFromEnv
+func Environ() map[string]string {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+
+	return env
+}
+
+// ContextsFromEnv reconstructs the `github`, `runner`, `env`, and `inputs` contexts from the
+// environment of a process run by a step, so expressions can be evaluated with the data the
+// runner had. The `github` and `runner` contexts are read from the default environment variables,
+// and the event payload from the file at GITHUB_EVENT_PATH. Inputs are read from the payload of
+// `workflow_dispatch` events and from the INPUT_ variables set for actions.
+//
+// The runner doesn't tell apart variables of the `env` context and other variables of the
+// process, so the `env` context contains all variables except for the default ones, including
+// variables like PATH.
+func ContextsFromEnv(env map[string]string) (expr.ContextData, error) {
+	github := expr.ContextData{}
+	runner := expr.ContextData{}
+	contexts := map[string]expr.ContextData{"github": github, "runner": runner}
+
+	defaults := map[string]bool{"CI": true, "GITHUB_ACTIONS": true}
+	for _, e := range defaultEnv {
+		defaults[e.name] = true
+
+		v, ok := env[e.name]
+		if !ok {
+			continue
+		}
+
+		if e.property == "ref_protected" {
+			contexts[e.context][e.property] = v == "true"
+		} else {
+			contexts[e.context][e.property] = v
+		}
+	}
+
+	github["event"] = expr.ContextData{}
+	if path := env["GITHUB_EVENT_PATH"]; path != "" {
+		event, err := LoadEvent(env["GITHUB_EVENT_NAME"], path)
+		if err != nil {
+			return nil, err
+		}
+		github["event"] = event.Payload
+	}
+
+	inputs := expr.ContextData{}
+	if i, ok := github["event"].(expr.ContextData)["inputs"].(expr.ContextData); ok && env["GITHUB_EVENT_NAME"] == "workflow_dispatch" {
+		inputs = copyContext(i)
+	}
+
+	envCtx := expr.ContextData{}
+	for k, v := range env {
+		switch {
+		case strings.HasPrefix(k, "INPUT_"):
+			inputs[strings.ToLower(strings.TrimPrefix(k, "INPUT_"))] = v
+		case defaults[k]:
+		default:
+			envCtx[k] = v
+		}
+	}
+
+	return expr.ContextData{
+		"github": github,
+		"runner": runner,
+		"env":    envCtx,
+		"inputs": inputs,
+	}, nil
+}
+
+// JobEnv returns the env context of a run of the job: the variables of the workflow's `env:`
+// section, overridden by the ones of the job. Values are evaluated with the contexts available to
+// each section, see expr.LookupAvailability. Ctx are the contexts of the run including `matrix`
+// and `strategy`. The result is expr.Unknown if the variables depend on values only known at
+// runtime.
+//
+// Names are kept as written in the workflow, see restoreEnvNames. Lookups in the env context are
+// case insensitive.
+func JobEnv(w *actionlint.Workflow, job *actionlint.Job, ctx expr.ContextData) (interface{}, error) {
+	wenv, err := evaluateEnv(w.Env, ctx, "env")
+	if err != nil {
+		return nil, fmt.Errorf("workflow env: %v", err)
+	}
+
+	jenv, err := evaluateEnv(job.Env, ctx, "jobs.<job_id>.env")
+	if err != nil {
+		return nil, fmt.Errorf("env of job %q: %v", job.ID.Value, err)
+	}
+
+	return mergeEnv(wenv, jenv), nil
+}
+
+// StepEnv returns the env context of the step. The `env` context in ctx is the env of the job, see
+// JobEnv. It's overridden by the variables earlier steps wrote to GITHUB_ENV, which are overridden
+// by the variables of the step's `env:` section in turn.
+func StepEnv(step *actionlint.Step, ctx expr.ContextData, githubEnv interface{}) (interface{}, error) {
+	return stepEnv(step, ctx, githubEnv, jobStepsKey)
+}
+
+// stepEnv is like StepEnv for the steps at the given key, see jobStepsKey and compositeStepsKey
+func stepEnv(step *actionlint.Step, ctx expr.ContextData, githubEnv interface{}, key string) (interface{}, error) {
+	env := mergeEnv(ctx["env"], githubEnv)
+
+	sctx := copyContext(ctx)
+	sctx["env"] = env
+
+	senv, err := evaluateEnv(step.Env, sctx, key+".env")
+	if err != nil {
+		return nil, fmt.Errorf("env of step %q: %v", stepName(step), err)
+	}
+
+	return mergeEnv(env, senv), nil
+}
+
+// evaluateEnv evaluates the values of an `env:` section at the given workflow key
+func evaluateEnv(env *actionlint.Env, ctx expr.ContextData, location string) (interface{}, error) {
+	if env == nil {
+		return expr.ContextData{}, nil
+	}
+
+	if env.Expression != nil {
+		v, err := evaluateString(env.Expression.Value, ctx, location)
+		if err != nil {
+			return nil, err
+		}
+
+		if v == expr.Unknown {
+			return expr.Unknown, nil
+		}
+
+		vars, ok := v.(expr.ContextData)
+		if !ok {
+			return nil, fmt.Errorf("%s does not evaluate to an object", env.Expression.Value)
+		}
+
+		return vars, nil
+	}
+
+	vars := make(expr.ContextData, len(env.Vars))
+	for _, ev := range env.Vars {
+		if ev.Value == nil {
+			vars[ev.Name.Value] = ""
+			continue
+		}
+
+		v, err := evaluateString(ev.Value.Value, ctx, location)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ev.Name.Value, err)
+		}
+
+		vars[ev.Name.Value] = envValue(v)
+	}
+
+	return vars, nil
+}
+
+// envValue converts the value of a variable to a string. Unknown values and values that can't
+// be converted stay as they are.
+func envValue(v interface{}) interface{} {
+	if r := expr.NewEvaluationResult(v); v != expr.Unknown && r.Primitive() {
+		return r.CoerceString()
+	}
+
+	return v
+}
+
+// mergeEnv merges env contexts, variables of later contexts override earlier ones. If any of them
+// is unknown, the result is unknown.
+func mergeEnv(envs ...interface{}) interface{} {
+	r := expr.ContextData{}
+
+	for _, e := range envs {
+		vars, ok := e.(expr.ContextData)
+		if !ok {
+			return expr.Unknown
+		}
+
+		for k, v := range vars {
+			r[k] = v
+		}
+	}
+
+	return r
+}
+
+// githubEnvWrite matches a line of a script writing a variable to GITHUB_ENV, like
+// `echo "NAME=value" >> $GITHUB_ENV`
+var githubEnvWrite = regexp.MustCompile(`^\s*echo\s+(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))\s*>>\s*"?(?:\$GITHUB_ENV|\$\{GITHUB_ENV\}|\$env:GITHUB_ENV)"?\s*$`)
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var expressionPattern = regexp.MustCompile(`\$\{\{.*?\}\}`)
+
+// githubEnvWrites returns the variables a `run:` step writes to GITHUB_ENV. Only `echo` commands
+// with a literal name are recognized, values using shell expansions are unknown. The result is
+// expr.Unknown if the script writes to GITHUB_ENV in another way. Names are kept as written. Key
+// is the workflow key of the steps, see jobStepsKey.
+func githubEnvWrites(step *actionlint.Step, ctx expr.ContextData, key string) interface{} {
+	vars := expr.ContextData{}
+
+	run, ok := step.Exec.(*actionlint.ExecRun)
+	if !ok || run.Run == nil {
+		return vars
+	}
+
+	for _, line := range strings.Split(run.Run.Value, "\n") {
+		if !strings.Contains(line, "GITHUB_ENV") {
+			continue
+		}
+
+		m := githubEnvWrite.FindStringSubmatch(line)
+		if m == nil {
+			return expr.Unknown
+		}
+
+		content, singleQuoted := m[1]+m[3], m[2] != ""
+		if singleQuoted {
+			content = m[2]
+		}
+
+		name, value, ok := strings.Cut(content, "=")
+		if !ok {
+			// Multiline values use a delimiter like `NAME<<EOF`
+			if n, _, ok := strings.Cut(content, "<<"); ok && envName.MatchString(n) {
+				vars[n] = expr.Unknown
+				continue
+			}
+			return expr.Unknown
+		}
+
+		if !envName.MatchString(name) {
+			return expr.Unknown
+		}
+
+		// Expressions are replaced before the script runs, shell expansions only at runtime
+		if !singleQuoted && strings.ContainsAny(expressionPattern.ReplaceAllString(value, ""), "$`") {
+			vars[name] = expr.Unknown
+			continue
+		}
+
+		v, err := evaluateString(value, ctx, key+".run")
+		if err != nil {
+			v = expr.Unknown
+		}
+		vars[name] = envValue(v)
+	}
+
+	return vars
+}
+
+// restoreEnvNames restores the names of the variables of the `env:` sections of the workflow as
+// written, actionlint stores them in lower case. Names are taken from the mapping keys of its
+// source at the same positions.
+func restoreEnvNames(w *actionlint.Workflow, doc *yaml.Node) {
+	keys := map[actionlint.Pos]string{}
+
+	var collect func(n *yaml.Node)
+	collect = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := n.Content[i]
+				keys[actionlint.Pos{Line: k.Line, Col: k.Column}] = k.Value
+			}
+		}
+		for _, c := range n.Content {
+			collect(c)
+		}
+	}
+	collect(doc)
+
+	restore := func(env *actionlint.Env) {
+		if env == nil {
+			return
+		}
+
+		for _, ev := range env.Vars {
+			if name, ok := keys[*ev.Name.Pos]; ok && strings.EqualFold(name, ev.Name.Value) {
+				ev.Name.Value = name
+			}
+		}
+	}
+
+	restore(w.Env)
+	for _, j := range w.Jobs {
+		restore(j.Env)
+		for _, s := range j.Steps {
+			restore(s.Env)
+		}
+	}
+}
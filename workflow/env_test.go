@@ -0,0 +1,234 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestDefaultEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  expr.ContextData
+		want expr.ContextData
+	}{
+		{
+			name: "no contexts",
+			ctx:  expr.ContextData{},
+			want: expr.ContextData{"CI": "true", "GITHUB_ACTIONS": "true"},
+		},
+		{
+			name: "values",
+			ctx: expr.ContextData{
+				"github": expr.ContextData{
+					"ref":           "refs/heads/main",
+					"sha":           "abc",
+					"ref_protected": true,
+					"run_attempt":   "1",
+					"event":         expr.ContextData{"ref": "refs/heads/main"},
+					"event_path":    "/home/runner/work/_temp/_github_workflow/event.json",
+					"workspace":     expr.Unknown,
+				},
+				"runner": expr.ContextData{"os": "Linux", "arch": "X64", "temp": "/home/runner/work/_temp", "debug": nil},
+			},
+			want: expr.ContextData{
+				"CI":                   "true",
+				"GITHUB_ACTIONS":       "true",
+				"GITHUB_REF":           "refs/heads/main",
+				"GITHUB_SHA":           "abc",
+				"GITHUB_REF_PROTECTED": "true",
+				"GITHUB_RUN_ATTEMPT":   "1",
+				"GITHUB_EVENT_PATH":    "/home/runner/work/_temp/_github_workflow/event.json",
+				"GITHUB_WORKSPACE":     expr.Unknown,
+				"RUNNER_OS":            "Linux",
+				"RUNNER_ARCH":          "X64",
+				"RUNNER_TEMP":          "/home/runner/work/_temp",
+			},
+		},
+		{
+			name: "unknown runner",
+			ctx:  expr.ContextData{"runner": expr.Unknown},
+			want: expr.ContextData{
+				"CI":                 "true",
+				"GITHUB_ACTIONS":     "true",
+				"RUNNER_ARCH":        expr.Unknown,
+				"RUNNER_DEBUG":       expr.Unknown,
+				"RUNNER_ENVIRONMENT": expr.Unknown,
+				"RUNNER_NAME":        expr.Unknown,
+				"RUNNER_OS":          expr.Unknown,
+				"RUNNER_TEMP":        expr.Unknown,
+				"RUNNER_TOOL_CACHE":  expr.Unknown,
+				"RUNNER_WORKSPACE":   expr.Unknown,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DefaultEnv(tt.ctx); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DefaultEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultEnv_Job(t *testing.T) {
+	ctx := expr.ContextData{
+		"github": GitHubContext(&Event{Name: "push", Payload: expr.ContextData{}}, Repository{}, Run{}),
+		"runner": LookupRunnerProfile("ubuntu-latest").Context(),
+	}
+
+	// Variables naming files of the runner are only known at runtime
+	env := DefaultEnv(ctx)
+	for _, k := range []string{"GITHUB_ENV", "GITHUB_OUTPUT", "GITHUB_PATH", "GITHUB_STATE", "GITHUB_STEP_SUMMARY", "RUNNER_WORKSPACE"} {
+		if v, ok := env[k]; !ok || v != expr.Unknown {
+			t.Errorf("DefaultEnv()[%q] = %v, want unknown", k, v)
+		}
+	}
+}
+
+func TestContextsFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(path, []byte(`{"ref": "refs/heads/main", "inputs": {"level": "debug"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"CI":                   "true",
+		"GITHUB_ACTIONS":       "true",
+		"GITHUB_EVENT_NAME":    "workflow_dispatch",
+		"GITHUB_EVENT_PATH":    path,
+		"GITHUB_REF":           "refs/heads/main",
+		"GITHUB_REF_PROTECTED": "false",
+		"GITHUB_RUN_ATTEMPT":   "2",
+		"RUNNER_OS":            "Linux",
+		"GITHUB_OUTPUT":        "/home/runner/work/_temp/_runner_file_commands/set_output_1",
+		"GITHUB_STATE":         "/home/runner/work/_temp/_runner_file_commands/save_state_1",
+		"GITHUB_STEP_SUMMARY":  "/home/runner/work/_temp/_runner_file_commands/step_summary_1",
+		"RUNNER_WORKSPACE":     "/home/runner/work/repo",
+		"INPUT_LEVEL":          "info",
+		"INPUT_DRY-RUN":        "true",
+		"DEPLOY_TARGET":        "staging",
+	}
+
+	got, err := ContextsFromEnv(env)
+	if err != nil {
+		t.Fatalf("ContextsFromEnv() error = %v", err)
+	}
+
+	want := expr.ContextData{
+		"github": expr.ContextData{
+			"event_name":    "workflow_dispatch",
+			"event_path":    path,
+			"event":         expr.ContextData{"ref": "refs/heads/main", "inputs": expr.ContextData{"level": "debug"}},
+			"ref":           "refs/heads/main",
+			"ref_protected": false,
+			"run_attempt":   "2",
+			"output":        "/home/runner/work/_temp/_runner_file_commands/set_output_1",
+			"state":         "/home/runner/work/_temp/_runner_file_commands/save_state_1",
+			"step_summary":  "/home/runner/work/_temp/_runner_file_commands/step_summary_1",
+		},
+		"runner": expr.ContextData{"os": "Linux", "workspace": "/home/runner/work/repo"},
+		"env":    expr.ContextData{"DEPLOY_TARGET": "staging"},
+		"inputs": expr.ContextData{"level": "info", "dry-run": "true"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ContextsFromEnv() = %v, want %v", got, want)
+	}
+
+	// The default environment variables are derived again from the contexts
+	de := DefaultEnv(got)
+	for _, k := range []string{"CI", "GITHUB_EVENT_NAME", "GITHUB_REF", "GITHUB_REF_PROTECTED", "GITHUB_OUTPUT", "RUNNER_OS", "RUNNER_WORKSPACE"} {
+		if de[k] != env[k] {
+			t.Errorf("DefaultEnv()[%q] = %v, want %v", k, de[k], env[k])
+		}
+	}
+
+	if _, err := ContextsFromEnv(map[string]string{"GITHUB_EVENT_PATH": filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Errorf("ContextsFromEnv() error = nil, want error for missing event file")
+	}
+}
+
+func TestSimulate_Env(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+env:
+  TARGET: staging
+  REF: ${{ github.ref }}
+  Log_Level: debug
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        region: [eu]
+    env:
+      TARGET: production
+      REGION: ${{ matrix.region }}
+    steps:
+      - id: set
+        run: |
+          echo "VERSION=1.2.${{ github.run_number }}" >> $GITHUB_ENV
+          echo 'SUFFIX=-rc' >> "$GITHUB_ENV"
+          echo "BUILD=$(date)" >> $GITHUB_ENV
+      - env:
+          TARGET: ${{ env.TARGET }}-${{ env.REGION }}
+          SHORT: ${{ env.SUFFIX }}
+        if: env.TARGET == 'production-eu' && env.SUFFIX == '-rc'
+        run: ./deploy.sh
+      - run: cat .env >> $GITHUB_ENV
+      - run: echo done
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{
+		Contexts: expr.ContextData{"github": expr.ContextData{"ref": "refs/heads/main", "run_number": float64(7)}},
+	})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	steps := plan.Job("deploy").Runs[0].Steps
+
+	// Names are kept as written
+	want := []interface{}{
+		expr.ContextData{"TARGET": "production", "REF": "refs/heads/main", "Log_Level": "debug", "REGION": "eu"},
+		expr.ContextData{
+			"TARGET": "production-eu", "REF": "refs/heads/main", "Log_Level": "debug", "REGION": "eu", "SHORT": "-rc",
+			"VERSION": "1.2.7", "SUFFIX": "-rc", "BUILD": expr.Unknown,
+		},
+		expr.ContextData{"TARGET": "production", "REF": "refs/heads/main", "Log_Level": "debug", "REGION": "eu", "VERSION": "1.2.7", "SUFFIX": "-rc", "BUILD": expr.Unknown},
+		expr.Unknown,
+	}
+	for i, s := range steps {
+		if !reflect.DeepEqual(s.Env, want[i]) {
+			t.Errorf("step %d env = %v, want %v", i, s.Env, want[i])
+		}
+	}
+
+	if steps[1].Status != StatusRun {
+		t.Errorf("step 1 status = %v, want run", steps[1].Status)
+	}
+}
+
+func TestJobEnv_Availability(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+env:
+  A: ${{ matrix.os }}
+jobs:
+  a:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        os: [linux]
+    steps:
+      - run: echo
+`)
+
+	// The matrix context isn't available to the workflow's env
+	if _, err := JobEnv(w, w.Jobs["a"], expr.ContextData{"matrix": expr.ContextData{"os": "linux"}}); err == nil {
+		t.Errorf("JobEnv() error = nil, want error")
+	}
+}
@@ -0,0 +1,220 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+// Repository is metadata of the repository a workflow runs in. Fields that are not given are
+// read from the `repository` object of the event payload.
+type Repository struct {
+	// FullName is the owner and name of the repository, like `octocat/hello-world`
+	FullName string
+
+	DefaultBranch string
+
+	// ServerURL is the URL of the GitHub server. Defaults to https://github.com.
+	ServerURL string
+}
+
+// Run is metadata of a workflow run. Fields that are not given are unknown, except for the actors
+// which default to the sender of the event, and the attempt which defaults to the first one.
+type Run struct {
+	ID      string
+	Number  string
+	Attempt int
+
+	// Workflow is the name of the workflow
+	Workflow string
+
+	// Actor is the user that triggered the initial run, TriggeringActor the one that started the
+	// attempt, e.g. by re-running the workflow
+	Actor           string
+	TriggeringActor string
+}
+
+// LoadEvent reads the webhook payload of an event from a JSON file, like the file GitHub stores
+// at GITHUB_EVENT_PATH
+func LoadEvent(name string, path string) (*Event, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := expr.ContextData{}
+	if err := json.Unmarshal(src, &payload); err != nil {
+		return nil, fmt.Errorf("could not parse payload %s: %v", path, err)
+	}
+
+	return &Event{Name: name, Payload: payload}, nil
+}
+
+// GitHubContext returns the `github` context of a run triggered by the event. Fields GitHub
+// computes from the event, like `ref`, `sha`, `head_ref`, and `base_ref`, are derived from the
+// payload the way GitHub derives them for the event type. Values that can't be derived, like the
+// latest commit on the default branch for `issues` events, are unknown.
+func GitHubContext(event *Event, repo Repository, run Run) expr.ContextData {
+	payload := event.Payload
+	if payload == nil {
+		payload = expr.ContextData{}
+	}
+
+	str := func(path ...string) string {
+		switch v := lookupPath(payload, path...).(type) {
+		case string:
+			return v
+		case float64:
+			// fmt.Sprint formats IDs like 12345678 in exponent notation
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		return ""
+	}
+
+	// orUnknown returns the value, or unknown for empty values
+	orUnknown := func(v string) interface{} {
+		if v == "" {
+			return expr.Unknown
+		}
+		return v
+	}
+
+	if repo.FullName == "" {
+		repo.FullName = str("repository", "full_name")
+	}
+	if repo.DefaultBranch == "" {
+		repo.DefaultBranch = str("repository", "default_branch")
+	}
+	if repo.ServerURL == "" {
+		repo.ServerURL = "https://github.com"
+	}
+
+	owner := str("repository", "owner", "login")
+	if owner == "" && strings.Contains(repo.FullName, "/") {
+		owner = strings.SplitN(repo.FullName, "/", 2)[0]
+	}
+
+	if run.Actor == "" {
+		run.Actor = str("sender", "login")
+	}
+	if run.TriggeringActor == "" {
+		run.TriggeringActor = run.Actor
+	}
+	if run.Attempt == 0 {
+		run.Attempt = 1
+	}
+
+	apiURL, graphqlURL := "https://api.github.com", "https://api.github.com/graphql"
+	if repo.ServerURL != "https://github.com" {
+		apiURL, graphqlURL = repo.ServerURL+"/api/v3", repo.ServerURL+"/api/graphql"
+	}
+
+	ctx := expr.ContextData{
+		"event_name":          event.Name,
+		"event":               payload,
+		"repository":          orUnknown(repo.FullName),
+		"repository_owner":    orUnknown(owner),
+		"repositoryUrl":       expr.Unknown,
+		"server_url":          repo.ServerURL,
+		"api_url":             apiURL,
+		"graphql_url":         graphqlURL,
+		"actor":               orUnknown(run.Actor),
+		"triggering_actor":    orUnknown(run.TriggeringActor),
+		"run_id":              orUnknown(run.ID),
+		"run_number":          orUnknown(run.Number),
+		"run_attempt":         fmt.Sprint(run.Attempt),
+		"workflow":            orUnknown(run.Workflow),
+		"head_ref":            "",
+		"base_ref":            "",
+		"token":               expr.Unknown,
+		"job":                 expr.Unknown,
+		"action":              expr.Unknown,
+		"workspace":           expr.Unknown,
+		"retention_days":      expr.Unknown,
+		"secret_source":       expr.Unknown,
+		"event_path":          expr.Unknown,
+		"env":                 expr.Unknown,
+		"path":                expr.Unknown,
+		"output":              expr.Unknown,
+		"state":               expr.Unknown,
+		"step_summary":        expr.Unknown,
+		"action_path":         expr.Unknown,
+		"action_repository":   expr.Unknown,
+		"workflow_ref":        expr.Unknown,
+		"workflow_sha":        expr.Unknown,
+		"ref_protected":       expr.Unknown,
+		"repository_id":       orUnknown(str("repository", "id")),
+		"repository_owner_id": orUnknown(str("repository", "owner", "id")),
+		"actor_id":            orUnknown(str("sender", "id")),
+	}
+	if repo.FullName != "" {
+		ctx["repositoryUrl"] = strings.Replace(repo.ServerURL, "https://", "git://", 1) + "/" + repo.FullName + ".git"
+	}
+
+	// ref and sha of the run, the latest commit on the default branch if the event doesn't define
+	// them
+	ref, sha := "", ""
+	if repo.DefaultBranch != "" {
+		ref = "refs/heads/" + repo.DefaultBranch
+	}
+
+	switch event.Name {
+	case "push":
+		ref, sha = str("ref"), str("after")
+
+	case "pull_request", "pull_request_review", "pull_request_review_comment":
+		// Runs check out the merge commit of the pull request
+		ref, sha = "", str("pull_request", "merge_commit_sha")
+		if n := str("pull_request", "number"); n != "" {
+			ref = "refs/pull/" + n + "/merge"
+		}
+		ctx["head_ref"], ctx["base_ref"] = str("pull_request", "head", "ref"), str("pull_request", "base", "ref")
+
+	case "pull_request_target":
+		// Runs check out the base branch of the pull request
+		ref, sha = "", str("pull_request", "base", "sha")
+		if b := str("pull_request", "base", "ref"); b != "" {
+			ref = "refs/heads/" + b
+		}
+		ctx["head_ref"], ctx["base_ref"] = str("pull_request", "head", "ref"), str("pull_request", "base", "ref")
+
+	case "create":
+		switch str("ref_type") {
+		case "branch":
+			ref = "refs/heads/" + str("ref")
+		case "tag":
+			ref = "refs/tags/" + str("ref")
+		}
+
+	case "release":
+		ref = ""
+		if t := str("release", "tag_name"); t != "" {
+			ref = "refs/tags/" + t
+		}
+
+	case "workflow_dispatch":
+		ref = str("ref")
+
+	case "merge_group":
+		ref, sha = str("merge_group", "head_ref"), str("merge_group", "head_sha")
+	}
+
+	ctx["ref"], ctx["sha"] = orUnknown(ref), orUnknown(sha)
+
+	switch {
+	case strings.HasPrefix(ref, "refs/heads/"):
+		ctx["ref_name"], ctx["ref_type"] = strings.TrimPrefix(ref, "refs/heads/"), "branch"
+	case strings.HasPrefix(ref, "refs/tags/"):
+		ctx["ref_name"], ctx["ref_type"] = strings.TrimPrefix(ref, "refs/tags/"), "tag"
+	case strings.HasPrefix(ref, "refs/pull/"):
+		ctx["ref_name"], ctx["ref_type"] = strings.TrimPrefix(ref, "refs/pull/"), "branch"
+	default:
+		ctx["ref_name"], ctx["ref_type"] = expr.Unknown, expr.Unknown
+	}
+
+	return ctx
+}
@@ -0,0 +1,125 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestGitHubContext(t *testing.T) {
+	repository := expr.ContextData{
+		"full_name":      "octo-org/hello",
+		"default_branch": "main",
+		"id":             float64(42),
+		"owner":          expr.ContextData{"login": "octo-org", "id": float64(7)},
+	}
+	sender := expr.ContextData{"login": "octocat", "id": float64(1)}
+
+	pullRequest := expr.ContextData{
+		"number":           float64(12),
+		"merge_commit_sha": "merge",
+		"head":             expr.ContextData{"ref": "feature", "sha": "head"},
+		"base":             expr.ContextData{"ref": "main", "sha": "base"},
+	}
+
+	tests := []struct {
+		name  string
+		event *Event
+		repo  Repository
+		want  map[string]interface{}
+	}{
+		{
+			name:  "branch push",
+			event: &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/dev", "after": "abc", "repository": repository, "sender": sender}},
+			want: map[string]interface{}{
+				"ref": "refs/heads/dev", "ref_name": "dev", "ref_type": "branch", "sha": "abc", "head_ref": "", "base_ref": "",
+				"repository": "octo-org/hello", "repository_owner": "octo-org", "repository_id": "42", "repository_owner_id": "7",
+				"actor": "octocat", "triggering_actor": "octocat", "actor_id": "1", "event_name": "push", "run_attempt": "1",
+			},
+		},
+		{
+			name:  "tag push",
+			event: &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/tags/v1.2.0", "after": "abc"}},
+			want:  map[string]interface{}{"ref": "refs/tags/v1.2.0", "ref_name": "v1.2.0", "ref_type": "tag", "repository": expr.Unknown, "actor": expr.Unknown},
+		},
+		{
+			name:  "pull request",
+			event: &Event{Name: "pull_request", Payload: expr.ContextData{"action": "opened", "pull_request": pullRequest, "repository": repository}},
+			want:  map[string]interface{}{"ref": "refs/pull/12/merge", "ref_name": "12/merge", "ref_type": "branch", "sha": "merge", "head_ref": "feature", "base_ref": "main"},
+		},
+		{
+			name: "large ids",
+			event: &Event{Name: "pull_request", Payload: expr.ContextData{
+				"action":       "opened",
+				"pull_request": expr.ContextData{"number": float64(12345678), "merge_commit_sha": "merge"},
+				"repository":   expr.ContextData{"full_name": "octo-org/hello", "id": float64(987654321)},
+				"sender":       expr.ContextData{"login": "octocat", "id": float64(58338429)},
+			}},
+			want: map[string]interface{}{"ref": "refs/pull/12345678/merge", "ref_name": "12345678/merge", "repository_id": "987654321", "actor_id": "58338429"},
+		},
+		{
+			name:  "pull request target",
+			event: &Event{Name: "pull_request_target", Payload: expr.ContextData{"action": "opened", "pull_request": pullRequest, "repository": repository}},
+			want:  map[string]interface{}{"ref": "refs/heads/main", "ref_name": "main", "sha": "base", "head_ref": "feature", "base_ref": "main"},
+		},
+		{
+			name:  "release",
+			event: &Event{Name: "release", Payload: expr.ContextData{"release": expr.ContextData{"tag_name": "v2"}, "repository": repository}},
+			want:  map[string]interface{}{"ref": "refs/tags/v2", "ref_name": "v2", "ref_type": "tag", "sha": expr.Unknown},
+		},
+		{
+			name:  "create tag",
+			event: &Event{Name: "create", Payload: expr.ContextData{"ref": "v3", "ref_type": "tag"}},
+			want:  map[string]interface{}{"ref": "refs/tags/v3", "ref_type": "tag"},
+		},
+		{
+			name:  "default branch",
+			event: &Event{Name: "issues", Payload: expr.ContextData{"action": "opened"}},
+			repo:  Repository{FullName: "octo-org/other", DefaultBranch: "trunk", ServerURL: "https://ghe.example.com"},
+			want: map[string]interface{}{
+				"ref": "refs/heads/trunk", "ref_name": "trunk", "sha": expr.Unknown, "repository": "octo-org/other", "repository_owner": "octo-org",
+				"api_url": "https://ghe.example.com/api/v3", "repositoryUrl": "git://ghe.example.com/octo-org/other.git",
+			},
+		},
+		{
+			name:  "unknown default branch",
+			event: &Event{Name: "schedule", Payload: expr.ContextData{"schedule": "0 0 * * *"}},
+			want:  map[string]interface{}{"ref": expr.Unknown, "ref_name": expr.Unknown, "ref_type": expr.Unknown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GitHubContext(tt.event, tt.repo, Run{})
+			for k, want := range tt.want {
+				if !reflect.DeepEqual(got[k], want) {
+					t.Errorf("GitHubContext()[%q] = %v, want %v", k, got[k], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.json")
+	if err := os.WriteFile(path, []byte(`{"ref": "refs/heads/main", "commits": [{"id": "abc"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	event, err := LoadEvent("push", path)
+	if err != nil {
+		t.Fatalf("LoadEvent() error = %v", err)
+	}
+
+	want := &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/main", "commits": []interface{}{expr.ContextData{"id": "abc"}}}}
+	if !reflect.DeepEqual(event, want) {
+		t.Errorf("LoadEvent() = %v, want %v", event, want)
+	}
+
+	ctx := GitHubContext(event, Repository{}, Run{Actor: "octocat", TriggeringActor: "hubot", Attempt: 2})
+	if ctx["actor"] != "octocat" || ctx["triggering_actor"] != "hubot" || ctx["run_attempt"] != "2" {
+		t.Errorf("GitHubContext() actors = %v, %v, %v", ctx["actor"], ctx["triggering_actor"], ctx["run_attempt"])
+	}
+}
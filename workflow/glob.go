@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// compilePattern compiles a filter pattern of branches, tags, or paths filters into a regular
+// expression. Patterns use GitHub's filter pattern syntax:
+//
+//   - `*` matches zero or more characters, but not `/`
+//   - `**` matches zero or more of any character, `**/` zero or more directories
+//   - `?` matches zero or one of the preceding character
+//   - `+` matches one or more of the preceding character
+//   - `[]` matches one of the characters listed, or in the ranges, in the brackets
+//   - `\` escapes the following character
+//
+// A leading `!` negating the pattern has to be removed before.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+
+	// atom is set when the last written token can be repeated by `?` or `+`
+	atom := false
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+
+		switch c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				// Matches zero or more directories
+				b.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+			atom = false
+
+		case '?', '+':
+			if !atom {
+				return nil, errors.New("invalid pattern " + pattern + ": " + string(c) + " must follow a character")
+			}
+			b.WriteByte(c)
+			atom = false
+
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end <= 0 {
+				return nil, errors.New("invalid pattern " + pattern + ": unclosed [")
+			}
+
+			class := pattern[i+1 : i+1+end]
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+			atom = true
+
+		case '\\':
+			if i+1 >= len(pattern) {
+				return nil, errors.New("invalid pattern " + pattern + ": trailing \\")
+			}
+			b.WriteString(regexp.QuoteMeta(string(pattern[i+1])))
+			i++
+			atom = true
+
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+			atom = true
+		}
+	}
+
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// MatchPattern returns true if the value matches the filter pattern. Negated patterns starting
+// with `!` are not supported, use MatchPatterns for lists of patterns.
+func MatchPattern(pattern string, value string) (bool, error) {
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+
+	return re.MatchString(value), nil
+}
+
+// MatchPatterns returns true if the value matches the list of filter patterns. Patterns are
+// checked in order and the last matching pattern decides, so a negated pattern starting with `!`
+// excludes values matched by earlier patterns and can be overridden by later ones.
+func MatchPatterns(patterns []string, value string) (bool, error) {
+	matched := false
+
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		if negated {
+			p = p[1:]
+		}
+
+		m, err := MatchPattern(p, value)
+		if err != nil {
+			return false, err
+		}
+
+		if m {
+			matched = !negated
+		}
+	}
+
+	return matched, nil
+}
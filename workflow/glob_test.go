@@ -0,0 +1,105 @@
+package workflow
+
+import (
+	"testing"
+)
+
+func TestMatchPatterns(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		value    string
+		want     bool
+	}{
+		// Branches and tags
+		{[]string{"feature/*"}, "feature/my-branch", true},
+		{[]string{"feature/*"}, "feature/your-branch", true},
+		{[]string{"feature/*"}, "feature/a/b", false},
+		{[]string{"feature/**"}, "feature/beta-a/my-branch", true},
+		{[]string{"feature/**"}, "feature/mona/the/octocat", true},
+		{[]string{"main"}, "main", true},
+		{[]string{"main"}, "main2", false},
+		{[]string{"releases/mona-the-octocat"}, "releases/mona-the-octocat", true},
+		{[]string{"*"}, "main", true},
+		{[]string{"*"}, "releases/v1", false},
+		{[]string{"**"}, "releases/v1", true},
+		{[]string{"*feature"}, "mona-feature", true},
+		{[]string{"*feature"}, "feature", true},
+		{[]string{"*feature"}, "ver-10-feature", true},
+		{[]string{"v2*"}, "v2", true},
+		{[]string{"v2*"}, "v2.0", true},
+		{[]string{"v2*"}, "v2.9", true},
+		{[]string{"v2*"}, "v3", false},
+		{[]string{"v[12].[0-9]+.[0-9]+"}, "v1.10.1", true},
+		{[]string{"v[12].[0-9]+.[0-9]+"}, "v2.0.0", true},
+		{[]string{"v[12].[0-9]+.[0-9]+"}, "v3.0.0", false},
+		{[]string{"v[12].[0-9]+.[0-9]+"}, "v1..0", false},
+		{[]string{"v1.?"}, "v1", true},
+		{[]string{"v1.?"}, "v1.", true},
+		{[]string{"v1.?"}, "v1-", false},
+		{[]string{`feature\*`}, "feature*", true},
+		{[]string{`feature\*`}, "feature1", false},
+
+		// Paths
+		{[]string{"*"}, "README.md", true},
+		{[]string{"*"}, "docs/README.md", false},
+		{[]string{"*.jsx?"}, "page.js", true},
+		{[]string{"*.jsx?"}, "page.jsx", true},
+		{[]string{"*.jsx?"}, "page.jsxx", false},
+		{[]string{"**"}, "all/the/files.md", true},
+		{[]string{"*.js"}, "app.js", true},
+		{[]string{"*.js"}, "js/index.js", false},
+		{[]string{"**.js"}, "index.js", true},
+		{[]string{"**.js"}, "js/index.js", true},
+		{[]string{"**.js"}, "src/js/app.js", true},
+		{[]string{"docs/*"}, "docs/README.md", true},
+		{[]string{"docs/*"}, "docs/mona/octocat.txt", false},
+		{[]string{"docs/**"}, "docs/mona/octocat.txt", true},
+		{[]string{"docs/**/*.md"}, "docs/README.md", true},
+		{[]string{"docs/**/*.md"}, "docs/mona/hello-world.md", true},
+		{[]string{"docs/**/*.md"}, "docs/a/markdown/file.md", true},
+		{[]string{"docs/**/*.md"}, "docs/a/file.txt", false},
+		{[]string{"**/docs/**"}, "docs/hello.md", true},
+		{[]string{"**/docs/**"}, "dir/docs/my-file.txt", true},
+		{[]string{"**/docs/**"}, "space/docs/plan/space.doc", true},
+		{[]string{"**/docs/**"}, "mydocs/file.txt", false},
+		{[]string{"**/README.md"}, "README.md", true},
+		{[]string{"**/README.md"}, "js/README.md", true},
+		{[]string{"**/*src/**"}, "a/src/app.js", true},
+		{[]string{"**/*src/**"}, "my-src/code/js/app.js", true},
+		{[]string{"**/*-post.md"}, "my-post.md", true},
+		{[]string{"**/*-post.md"}, "path/their-post.md", true},
+		{[]string{"**/migrate-*.sql"}, "migrate-10909.sql", true},
+		{[]string{"**/migrate-*.sql"}, "db/migrate-v1.0.sql", true},
+		{[]string{"**/migrate-*.sql"}, "db/sept/migrate-v1.sql", true},
+
+		// Negation, the last matching pattern decides
+		{[]string{"*.md", "!README.md"}, "hello.md", true},
+		{[]string{"*.md", "!README.md"}, "README.md", false},
+		{[]string{"*.md", "!README.md"}, "docs/hello.md", false},
+		{[]string{"*.md", "!README.md", "README*"}, "README.md", true},
+		{[]string{"*.md", "!README.md", "README*"}, "README.doc", true},
+		{[]string{"releases/**", "!releases/**-alpha"}, "releases/v1", true},
+		{[]string{"releases/**", "!releases/**-alpha"}, "releases/v1-alpha", false},
+		{[]string{"!releases/**"}, "releases/v1", false},
+		{[]string{}, "main", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := MatchPatterns(tt.patterns, tt.value)
+			if err != nil {
+				t.Fatalf("MatchPatterns() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MatchPatterns(%v, %q) = %v, want %v", tt.patterns, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPattern_Invalid(t *testing.T) {
+	for _, p := range []string{"+abc", "v[12", `trailing\`} {
+		if _, err := MatchPattern(p, "x"); err == nil {
+			t.Errorf("MatchPattern(%q) error = nil, want error", p)
+		}
+	}
+}
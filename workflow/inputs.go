@@ -0,0 +1,276 @@
+package workflow
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+	"gopkg.in/yaml.v3"
+)
+
+// dispatchInputTypeNumber is the type of `number` inputs of `workflow_dispatch` events, which
+// actionlint v1.6.10 doesn't know, see ParseWorkflow
+const dispatchInputTypeNumber actionlint.WorkflowDispatchEventInputType = 0xff
+
+// DispatchInputs validates the values supplied for the inputs of a `workflow_dispatch` event
+// against the inputs declared by the workflow. Values are strings, like the values sent by the UI
+// or the API. Inputs without a value use their default. It returns the `inputs` context, where
+// boolean and number inputs are booleans and numbers, and the legacy `github.event.inputs` form
+// where all values are strings.
+//
+// actionlint v1.6.10 doesn't know `number` inputs of `workflow_dispatch` events, workflows
+// declaring them must be parsed with ParseWorkflow.
+func DispatchInputs(w *actionlint.Workflow, values map[string]string) (inputs expr.ContextData, eventInputs expr.ContextData, err error) {
+	dispatch := dispatchEvent(w)
+	if dispatch == nil {
+		return nil, nil, fmt.Errorf("workflow is not triggered by workflow_dispatch events")
+	}
+
+	// Input names are case insensitive, actionlint stores them in lower case
+	supplied := map[string]string{}
+	for name, v := range values {
+		if _, ok := dispatch.Inputs[strings.ToLower(name)]; !ok {
+			return nil, nil, fmt.Errorf("unexpected input %q", name)
+		}
+		supplied[strings.ToLower(name)] = v
+	}
+
+	inputs, eventInputs = expr.ContextData{}, expr.ContextData{}
+	for _, name := range sortedKeys(dispatch.Inputs) {
+		in := dispatch.Inputs[name]
+
+		v, ok := supplied[name]
+		if !ok && in.Default != nil {
+			v, ok = in.Default.Value, true
+		}
+		if !ok {
+			if in.Required != nil && in.Required.Value {
+				return nil, nil, fmt.Errorf("input %q is required", name)
+			}
+		}
+
+		switch in.Type {
+		case actionlint.WorkflowDispatchEventInputTypeBoolean:
+			b := false
+			if ok {
+				if b, err = parseBoolInput(name, v); err != nil {
+					return nil, nil, err
+				}
+			}
+			inputs[name], eventInputs[name] = b, strconv.FormatBool(b)
+
+		case dispatchInputTypeNumber:
+			n := float64(0)
+			if ok {
+				if n, err = parseNumberInput(name, v); err != nil {
+					return nil, nil, err
+				}
+			}
+			inputs[name], eventInputs[name] = n, v
+
+		case actionlint.WorkflowDispatchEventInputTypeChoice:
+			if ok && !containsValue(in.Options, v) {
+				return nil, nil, fmt.Errorf("input %q must be one of %s, got %q", name, strings.Join(stringValues(in.Options), ", "), v)
+			}
+			inputs[name], eventInputs[name] = v, v
+
+		default:
+			inputs[name], eventInputs[name] = v, v
+		}
+	}
+
+	return inputs, eventInputs, nil
+}
+
+// CallInputs validates the values supplied by the `with:` of a caller for the inputs of a
+// `workflow_call` event against the inputs declared by the workflow, and returns the `inputs`
+// context. Values may be strings or already typed values. Defaults are evaluated with the
+// contexts available to them, ctx are the contexts of the called workflow. Unknown values stay
+// unknown.
+func CallInputs(w *actionlint.Workflow, values map[string]interface{}, ctx expr.ContextData) (expr.ContextData, error) {
+	call := callEvent(w)
+	if call == nil {
+		return nil, fmt.Errorf("workflow is not triggered by workflow_call events")
+	}
+
+	declared := map[string]*actionlint.WorkflowCallEventInput{}
+	for name, in := range call.Inputs {
+		declared[strings.ToLower(name.Value)] = in
+	}
+
+	supplied := map[string]interface{}{}
+	for name, v := range values {
+		if _, ok := declared[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("unexpected input %q", name)
+		}
+		supplied[strings.ToLower(name)] = v
+	}
+
+	inputs := expr.ContextData{}
+
+	// Defaults may refer to the typed values of inputs ordered before them by name
+	dctx := copyContext(ctx)
+	dctx["inputs"] = inputs
+
+	for _, name := range sortedKeys(declared) {
+		in := declared[name]
+
+		v, ok := supplied[name]
+		if !ok && in.Default != nil {
+			d, err := evaluateString(in.Default.Value, dctx, "on.workflow_call.inputs.<inputs_id>.default")
+			if err != nil {
+				return nil, fmt.Errorf("default of input %q: %v", name, err)
+			}
+			v, ok = d, true
+		}
+		if !ok {
+			if in.Required != nil && in.Required.Value {
+				return nil, fmt.Errorf("input %q is required", name)
+			}
+		}
+
+		if v == expr.Unknown {
+			inputs[name] = expr.Unknown
+			continue
+		}
+
+		switch in.Type {
+		case actionlint.WorkflowCallEventInputTypeBoolean:
+			b := false
+			switch tv := v.(type) {
+			case bool:
+				b = tv
+			case string:
+				var err error
+				if b, err = parseBoolInput(name, tv); err != nil {
+					return nil, err
+				}
+			case nil:
+			default:
+				return nil, fmt.Errorf("input %q must be a boolean, got %v", name, v)
+			}
+			inputs[name] = b
+
+		case actionlint.WorkflowCallEventInputTypeNumber:
+			n := float64(0)
+			switch tv := v.(type) {
+			case float64:
+				n = tv
+			case string:
+				var err error
+				if n, err = parseNumberInput(name, tv); err != nil {
+					return nil, err
+				}
+			case nil:
+			default:
+				return nil, fmt.Errorf("input %q must be a number, got %v", name, v)
+			}
+			inputs[name] = n
+
+		default:
+			if v == nil {
+				v = ""
+			}
+			r := expr.NewEvaluationResult(v)
+			if !r.Primitive() {
+				return nil, fmt.Errorf("input %q must be a string, got %s", name, r.Type.String())
+			}
+			inputs[name] = r.CoerceString()
+		}
+	}
+
+	return inputs, nil
+}
+
+// parseNumberInput parses the value of a number input. Unlike strconv.ParseFloat, it only accepts
+// decimal numbers, not `NaN`, `Inf`, or hexadecimal numbers.
+func parseNumberInput(name string, v string) (float64, error) {
+	s := strings.TrimSpace(v)
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || strings.ContainsAny(s, "xX") {
+		return 0, fmt.Errorf("input %q must be a number, got %q", name, v)
+	}
+
+	return f, nil
+}
+
+// numberDispatchInputs returns the `type:` nodes of the `number` inputs of a `workflow_dispatch`
+// trigger in a workflow document by input name in lower case
+func numberDispatchInputs(doc *yaml.Node) map[string]*yaml.Node {
+	types := map[string]*yaml.Node{}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return types
+	}
+
+	inputs := mappingValue(mappingValue(mappingValue(doc.Content[0], "on"), "workflow_dispatch"), "inputs")
+	if inputs == nil || inputs.Kind != yaml.MappingNode {
+		return types
+	}
+
+	for i := 0; i+1 < len(inputs.Content); i += 2 {
+		if t := mappingValue(inputs.Content[i+1], "type"); t != nil && t.Kind == yaml.ScalarNode && t.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 && t.Value == "number" {
+			types[strings.ToLower(inputs.Content[i].Value)] = t
+		}
+	}
+
+	return types
+}
+
+// dispatchEvent returns the `workflow_dispatch` trigger of the workflow, or nil
+func dispatchEvent(w *actionlint.Workflow) *actionlint.WorkflowDispatchEvent {
+	for _, on := range w.On {
+		if e, ok := on.(*actionlint.WorkflowDispatchEvent); ok {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// callEvent returns the `workflow_call` trigger of the workflow, or nil
+func callEvent(w *actionlint.Workflow) *actionlint.WorkflowCallEvent {
+	for _, on := range w.On {
+		if e, ok := on.(*actionlint.WorkflowCallEvent); ok {
+			return e
+		}
+	}
+
+	return nil
+}
+
+func parseBoolInput(name string, v string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	return false, fmt.Errorf("input %q must be a boolean, got %q", name, v)
+}
+
+func containsValue(s []*actionlint.String, v string) bool {
+	for _, e := range s {
+		if e.Value == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
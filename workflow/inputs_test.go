@@ -0,0 +1,242 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestDispatchInputs(t *testing.T) {
+	w := parseWorkflow(t, `
+on:
+  workflow_dispatch:
+    inputs:
+      environment:
+        type: choice
+        options: [staging, production]
+        default: staging
+      dry-run:
+        type: boolean
+      debug:
+        type: boolean
+        default: true
+      version:
+        required: true
+      count:
+        type: 'number'
+        default: 2
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+	tests := []struct {
+		name      string
+		values    map[string]string
+		want      expr.ContextData
+		wantEvent expr.ContextData
+		wantErr   bool
+	}{
+		{
+			name:      "defaults",
+			values:    map[string]string{"version": "1.0"},
+			want:      expr.ContextData{"environment": "staging", "dry-run": false, "debug": true, "version": "1.0", "count": float64(2)},
+			wantEvent: expr.ContextData{"environment": "staging", "dry-run": "false", "debug": "true", "version": "1.0", "count": "2"},
+		},
+		{
+			name:      "values",
+			values:    map[string]string{"Environment": "production", "dry-run": "TRUE", "debug": "false", "version": "2.0", "count": "1.5e1"},
+			want:      expr.ContextData{"environment": "production", "dry-run": true, "debug": false, "version": "2.0", "count": float64(15)},
+			wantEvent: expr.ContextData{"environment": "production", "dry-run": "true", "debug": "false", "version": "2.0", "count": "1.5e1"},
+		},
+		{
+			name:    "required",
+			values:  map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid choice",
+			values:  map[string]string{"version": "1.0", "environment": "dev"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid boolean",
+			values:  map[string]string{"version": "1.0", "dry-run": "yes"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid number",
+			values:  map[string]string{"version": "1.0", "count": "ten"},
+			wantErr: true,
+		},
+		{
+			name:    "hexadecimal number",
+			values:  map[string]string{"version": "1.0", "count": "0x10"},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected",
+			values:  map[string]string{"version": "1.0", "other": "x"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotEvent, err := DispatchInputs(w, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DispatchInputs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DispatchInputs() inputs = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotEvent, tt.wantEvent) {
+				t.Errorf("DispatchInputs() event inputs = %v, want %v", gotEvent, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestCallInputs(t *testing.T) {
+	w := parseWorkflow(t, `
+on:
+  workflow_call:
+    inputs:
+      count:
+        description: count
+        type: number
+        default: 3
+      deploy:
+        description: deploy
+        type: boolean
+      ref:
+        description: ref
+        type: string
+        default: ${{ github.ref }}
+      label:
+        description: label
+        type: string
+        default: ${{ inputs.count }}-items
+      target:
+        description: target
+        type: string
+        required: true
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+	ctx := expr.ContextData{"github": expr.ContextData{"ref": "refs/heads/main"}, "vars": expr.ContextData{}}
+
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		want    expr.ContextData
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			values: map[string]interface{}{"target": "prod"},
+			want:   expr.ContextData{"count": float64(3), "deploy": false, "ref": "refs/heads/main", "label": "3-items", "target": "prod"},
+		},
+		{
+			name:   "values",
+			values: map[string]interface{}{"target": "prod", "count": "5", "deploy": true, "ref": expr.Unknown},
+			want:   expr.ContextData{"count": float64(5), "deploy": true, "ref": expr.Unknown, "label": "5-items", "target": "prod"},
+		},
+		{
+			name:    "required",
+			values:  map[string]interface{}{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid number",
+			values:  map[string]interface{}{"target": "prod", "count": "many"},
+			wantErr: true,
+		},
+		{
+			name:    "NaN",
+			values:  map[string]interface{}{"target": "prod", "count": "NaN"},
+			wantErr: true,
+		},
+		{
+			name:    "infinity",
+			values:  map[string]interface{}{"target": "prod", "count": "+Inf"},
+			wantErr: true,
+		},
+		{
+			name:    "hexadecimal number",
+			values:  map[string]interface{}{"target": "prod", "count": "0x1p4"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid boolean",
+			values:  map[string]interface{}{"target": "prod", "deploy": float64(1)},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected",
+			values:  map[string]interface{}{"target": "prod", "other": "x"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CallInputs(w, tt.values, ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CallInputs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CallInputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulate_DispatchInputs(t *testing.T) {
+	w := parseWorkflow(t, `
+on:
+  workflow_dispatch:
+    inputs:
+      deploy:
+        type: boolean
+        default: false
+jobs:
+  deploy:
+    if: inputs.deploy && github.event.inputs.deploy == 'true'
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+	for _, tt := range []struct {
+		inputs interface{}
+		want   Status
+	}{
+		{expr.ContextData{"deploy": "true"}, StatusRun},
+		{expr.ContextData{}, StatusSkipped},
+		{expr.Unknown, StatusUndecidable},
+	} {
+		plan, err := Simulate(w, &Event{Name: "workflow_dispatch", Payload: expr.ContextData{"inputs": tt.inputs}}, Options{})
+		if err != nil {
+			t.Fatalf("Simulate() error = %v", err)
+		}
+		if got := plan.Jobs[0].Status; got != tt.want {
+			t.Errorf("Simulate() with inputs %v = %v, want %v", tt.inputs, got, tt.want)
+		}
+	}
+
+	if _, err := Simulate(w, &Event{Name: "workflow_dispatch", Payload: expr.ContextData{"inputs": expr.ContextData{"deploy": "maybe"}}}, Options{}); err == nil {
+		t.Errorf("Simulate() error = nil, want error for invalid input")
+	}
+}
@@ -0,0 +1,251 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rhysd/actionlint"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a parsed workflow file
+type File struct {
+	// Path is the path of the file, relative to the directory it was loaded from
+	Path     string
+	Workflow *actionlint.Workflow
+
+	// Err is the error reading or parsing the file, Workflow is nil then
+	Err error
+}
+
+// LoadDir parses all workflow files in the directory, like `.github/workflows`. Files are
+// returned ordered by path. A file that can't be read or parsed doesn't fail the others, it's
+// returned with Err set.
+func LoadDir(dir string) ([]*File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []*File{}
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+
+		src, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			files = append(files, &File{Path: e.Name(), Err: err})
+			continue
+		}
+
+		w, err := ParseWorkflow(src)
+		if err != nil {
+			files = append(files, &File{Path: e.Name(), Err: fmt.Errorf("could not parse workflow %s: %v", e.Name(), err)})
+			continue
+		}
+
+		files = append(files, &File{Path: e.Name(), Workflow: w})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	return files, nil
+}
+
+// LoadWorkflow parses a single workflow file, see ParseWorkflow
+func LoadWorkflow(path string) (*actionlint.Workflow, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := ParseWorkflow(src)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse workflow %s: %v", path, err)
+	}
+
+	return w, nil
+}
+
+// inheritSecrets is the key marking jobs with `secrets: inherit` in the secrets of their
+// WorkflowCall. It's not a valid secret name.
+const inheritSecrets = "*"
+
+// ParseWorkflow parses a workflow with actionlint and returns its first error. actionlint v1.6.10
+// can't parse `secrets: inherit` of jobs calling reusable workflows, ParseWorkflow blanks these
+// entries before parsing and marks the jobs as inheriting secrets. Likewise, `number` inputs of
+// `workflow_dispatch` events are parsed as strings and marked as numbers. The entries are found in
+// the YAML document and patched in place, so positions in the workflow stay the same. It also
+// keeps the YAML tags of matrix values, so quoted values like "3.10" stay strings, and the case of
+// the names of `env:` variables.
+func ParseWorkflow(src []byte) (*actionlint.Workflow, error) {
+	// Invalid YAML is reported by actionlint below
+	var doc yaml.Node
+	docErr := yaml.Unmarshal(src, &doc)
+
+	inherit := map[string]*inheritEntry{}
+	numbers := map[string]*yaml.Node{}
+	if docErr == nil {
+		inherit = inheritSecretsJobs(&doc)
+		numbers = numberDispatchInputs(&doc)
+	}
+	if len(inherit) > 0 || len(numbers) > 0 {
+		src = append([]byte{}, src...)
+		for _, e := range inherit {
+			e.blank(src)
+		}
+		for _, t := range numbers {
+			// `string` has the same length as `number`, positions don't change
+			off := offsetOf(src, t.Line, t.Column)
+			if t.Style == yaml.DoubleQuotedStyle || t.Style == yaml.SingleQuotedStyle {
+				off++
+			}
+			copy(src[off:], "string")
+		}
+	}
+
+	w, errs := actionlint.Parse(src)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	if docErr != nil {
+		return nil, docErr
+	}
+
+	typeMatrixScalars(w, &doc)
+	restoreEnvNames(w, &doc)
+
+	if dispatch := dispatchEvent(w); dispatch != nil {
+		for name := range numbers {
+			if in, ok := dispatch.Inputs[name]; ok {
+				in.Type = dispatchInputTypeNumber
+			}
+		}
+	}
+
+	for id := range inherit {
+		if j, ok := w.Jobs[id]; ok && j.WorkflowCall != nil {
+			j.WorkflowCall.Secrets = map[string]*actionlint.WorkflowCallSecret{inheritSecrets: nil}
+		}
+	}
+
+	return w, nil
+}
+
+// inheritEntry is a `secrets: inherit` entry of a job
+type inheritEntry struct {
+	key, value *yaml.Node
+
+	// flow is set for jobs in flow style, like `{ uses: ./a.yml, secrets: inherit }`
+	flow bool
+}
+
+// blank removes the entry from the source, keeping the positions of everything else
+func (e *inheritEntry) blank(src []byte) {
+	start := offsetOf(src, e.key.Line, e.key.Column)
+	end := offsetOf(src, e.value.Line, e.value.Column) + scalarLength(e.value)
+
+	// Remove a separating comma of flow mappings as well
+	if e.flow {
+		next := end
+		for next < len(src) && strings.ContainsRune(" \t\r\n", rune(src[next])) {
+			next++
+		}
+
+		prev := start - 1
+		for prev >= 0 && strings.ContainsRune(" \t\r\n", rune(src[prev])) {
+			prev--
+		}
+
+		if next < len(src) && src[next] == ',' {
+			end = next + 1
+		} else if prev >= 0 && src[prev] == ',' {
+			start = prev
+		}
+	}
+
+	for i := start; i < end && i < len(src); i++ {
+		if src[i] != '\n' && src[i] != '\r' {
+			src[i] = ' '
+		}
+	}
+}
+
+// inheritSecretsJobs returns the `secrets: inherit` entries of the jobs in a workflow document by
+// job ID in lower case
+func inheritSecretsJobs(doc *yaml.Node) map[string]*inheritEntry {
+	entries := map[string]*inheritEntry{}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return entries
+	}
+
+	jobs := mappingValue(doc.Content[0], "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return entries
+	}
+
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		job := jobs.Content[i+1]
+		if job.Kind != yaml.MappingNode {
+			continue
+		}
+
+		for k := 0; k+1 < len(job.Content); k += 2 {
+			key, value := job.Content[k], job.Content[k+1]
+			if key.Value == "secrets" && value.Kind == yaml.ScalarNode && value.Value == "inherit" && value.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+				entries[strings.ToLower(jobs.Content[i].Value)] = &inheritEntry{key: key, value: value, flow: job.Style&yaml.FlowStyle != 0}
+			}
+		}
+	}
+
+	return entries
+}
+
+// mappingValue returns the value of the key in a mapping node, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// scalarLength returns the length in bytes of a single-line scalar in the source, including its
+// quotes
+func scalarLength(n *yaml.Node) int {
+	if n.Style == yaml.DoubleQuotedStyle || n.Style == yaml.SingleQuotedStyle {
+		return len(n.Value) + 2
+	}
+
+	return len(n.Value)
+}
+
+// offsetOf returns the byte offset of a 1-based line and column in the source. yaml.v3 counts
+// columns in characters, not bytes.
+func offsetOf(src []byte, line int, col int) int {
+	off := 0
+	for l := 1; l < line; l++ {
+		off += bytes.IndexByte(src[off:], '\n') + 1
+	}
+
+	for c := 1; c < col && off < len(src); c++ {
+		_, size := utf8.DecodeRune(src[off:])
+		off += size
+	}
+
+	return off
+}
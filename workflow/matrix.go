@@ -0,0 +1,743 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxMatrixJobs is the maximum number of jobs a matrix can produce
+const MaxMatrixJobs = 256
+
+// MatrixError is an invalid matrix, with the message GitHub reports when the workflow runs
+type MatrixError struct {
+	Job     string
+	Pos     *actionlint.Pos
+	Message string
+}
+
+func (e *MatrixError) Error() string {
+	if e.Pos == nil {
+		return fmt.Sprintf("Error when evaluating 'strategy' for job '%s'. %s", e.Job, e.Message)
+	}
+
+	return fmt.Sprintf("Error when evaluating 'strategy' for job '%s'. (Line: %d, Col: %d): %s", e.Job, e.Pos.Line, e.Pos.Col, e.Message)
+}
+
+// matrixVector is a matrix key with the values it takes
+type matrixVector struct {
+	key    string
+	values []interface{}
+}
+
+// Matrix is the expanded matrix of a job
+type Matrix struct {
+	// Combinations are ordered like GitHub orders them: the first vector varies slowest, and
+	// combinations added by `include` come last.
+	Combinations []expr.ContextData
+
+	// Keys are the keys of the vectors in the order they are defined, followed by the keys added
+	// by `include` entries
+	Keys []string
+
+	order keyOrder
+}
+
+// ExpandMatrix returns the expanded matrix of the job, or nil if the job doesn't use a matrix.
+// Expressions in the matrix are evaluated with the given contexts. Known is false if the matrix
+// depends on values only known at runtime, like the outputs of needed jobs.
+func ExpandMatrix(job *actionlint.Job, ctx expr.ContextData) (matrix *Matrix, known bool, err error) {
+	if job.Strategy == nil || job.Strategy.Matrix == nil {
+		return nil, true, nil
+	}
+
+	m := job.Strategy.Matrix
+	fail := func(format string, args ...interface{}) error {
+		return &MatrixError{Job: job.ID.Value, Pos: m.Pos, Message: fmt.Sprintf(format, args...)}
+	}
+
+	var vectors []*matrixVector
+	var include, exclude []interface{}
+	order := keyOrder{}
+
+	if m.Expression != nil {
+		v, err := evaluateMatrixString(m.Expression.Value, ctx, order)
+		if err != nil {
+			return nil, false, fail("%v", err)
+		}
+		if v == expr.Unknown {
+			return nil, false, nil
+		}
+
+		obj, ok := v.(expr.ContextData)
+		if !ok {
+			return nil, false, fail("Unexpected value '%s'", valueString(v))
+		}
+
+		vectors, include, exclude, err = matrixFromObject(obj, order, fail)
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		vectors, include, exclude, err = matrixFromYAML(m, ctx, order, fail)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	for _, v := range vectors {
+		if expr.ContainsUnknown(v.values) {
+			return nil, false, nil
+		}
+	}
+	for _, entries := range [][]interface{}{include, exclude} {
+		for _, e := range entries {
+			if obj, ok := includeObject(e); ok {
+				e = obj
+			}
+
+			if expr.ContainsUnknown(e) {
+				return nil, false, nil
+			}
+		}
+	}
+
+	combinations, err := combine(vectors, include, exclude, fail)
+	if err != nil {
+		return nil, false, err
+	}
+
+	matrix = &Matrix{Combinations: combinations, order: order}
+	for _, v := range vectors {
+		matrix.Keys = append(matrix.Keys, v.key)
+	}
+	for _, e := range include {
+		for _, k := range includeKeys(e, order) {
+			if _, ok := findKey(matrix.Keys, k); !ok {
+				matrix.Keys = append(matrix.Keys, k)
+			}
+		}
+	}
+
+	return matrix, true, nil
+}
+
+// matrixFromObject reads the vectors and the include and exclude lists of a matrix given as
+// expression, like `${{ fromJSON(needs.setup.outputs.matrix) }}`. Vectors are combined in the order
+// their keys are defined in the JSON document.
+func matrixFromObject(obj expr.ContextData, order keyOrder, fail func(string, ...interface{}) error) (vectors []*matrixVector, include, exclude []interface{}, err error) {
+	for _, k := range order.keys(obj) {
+		v := obj[k]
+
+		if v == expr.Unknown {
+			return []*matrixVector{{key: k, values: []interface{}{v}}}, nil, nil, nil
+		}
+
+		values, ok := v.([]interface{})
+		if !ok {
+			return nil, nil, nil, fail("Unexpected value '%s'", valueString(v))
+		}
+
+		switch strings.ToLower(k) {
+		case "include":
+			include = values
+		case "exclude":
+			exclude = values
+		default:
+			vectors = append(vectors, &matrixVector{key: k, values: values})
+		}
+	}
+
+	return vectors, include, exclude, nil
+}
+
+// matrixFromYAML reads the vectors and the include and exclude lists of a matrix, evaluating
+// the expressions used in its values
+func matrixFromYAML(m *actionlint.Matrix, ctx expr.ContextData, order keyOrder, fail func(string, ...interface{}) error) (vectors []*matrixVector, include, exclude []interface{}, err error) {
+	keys := make([]string, 0, len(m.Rows))
+	for k := range m.Rows {
+		keys = append(keys, k)
+	}
+
+	// Vectors are combined in the order they are defined. Rows given as expression have no name.
+	pos := func(r *actionlint.MatrixRow) *actionlint.Pos {
+		if r.Name != nil {
+			return r.Name.Pos
+		}
+		return r.Expression.Pos
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, pj := pos(m.Rows[keys[i]]), pos(m.Rows[keys[j]])
+		if pi.Line != pj.Line {
+			return pi.Line < pj.Line
+		}
+		return pi.Col < pj.Col
+	})
+
+	for _, k := range keys {
+		r := m.Rows[k]
+		var values []interface{}
+
+		if r.Expression != nil {
+			v, err := evaluateMatrixString(r.Expression.Value, ctx, order)
+			if err != nil {
+				return nil, nil, nil, fail("%v", err)
+			}
+
+			if v == expr.Unknown {
+				values = []interface{}{v}
+			} else if ar, ok := v.([]interface{}); ok {
+				values = ar
+			} else {
+				return nil, nil, nil, fail("Unexpected value '%s'", valueString(v))
+			}
+		} else {
+			for _, rv := range r.Values {
+				v, err := evaluateValue(rawYAMLValue(rv, order), ctx, order)
+				if err != nil {
+					return nil, nil, nil, fail("%v", err)
+				}
+				values = append(values, v)
+			}
+		}
+
+		vectors = append(vectors, &matrixVector{key: k, values: values})
+	}
+
+	if include, err = matrixCombinations(m.Include, ctx, order, fail); err != nil {
+		return nil, nil, nil, err
+	}
+
+	if exclude, err = matrixCombinations(m.Exclude, ctx, order, fail); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return vectors, include, exclude, nil
+}
+
+// matrixCombinations evaluates an `include` or `exclude` list
+func matrixCombinations(cs *actionlint.MatrixCombinations, ctx expr.ContextData, order keyOrder, fail func(string, ...interface{}) error) ([]interface{}, error) {
+	if cs == nil {
+		return nil, nil
+	}
+
+	if cs.Expression != nil {
+		v, err := evaluateMatrixString(cs.Expression.Value, ctx, order)
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+
+		if v == expr.Unknown {
+			return []interface{}{v}, nil
+		}
+
+		ar, ok := v.([]interface{})
+		if !ok {
+			return nil, fail("Unexpected value '%s'", valueString(v))
+		}
+
+		return ar, nil
+	}
+
+	r := make([]interface{}, 0, len(cs.Combinations))
+	for _, c := range cs.Combinations {
+		if c.Expression != nil {
+			v, err := evaluateMatrixString(c.Expression.Value, ctx, order)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+
+			r = append(r, v)
+			continue
+		}
+
+		obj := &orderedObject{ContextData: make(expr.ContextData, len(c.Assigns))}
+		for k, a := range c.Assigns {
+			v, err := evaluateValue(rawYAMLValue(a.Value, order), ctx, order)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			obj.ContextData[k] = v
+			obj.keys = append(obj.keys, a.Key)
+		}
+
+		sort.Slice(obj.keys, func(i, j int) bool {
+			pi, pj := obj.keys[i].Pos, obj.keys[j].Pos
+			if pi.Line != pj.Line {
+				return pi.Line < pj.Line
+			}
+			return pi.Col < pj.Col
+		})
+
+		r = append(r, obj)
+	}
+
+	return r, nil
+}
+
+// orderedObject is an `include` entry defined in YAML, which keeps the order of its keys
+type orderedObject struct {
+	expr.ContextData
+	keys []*actionlint.String
+}
+
+// includeObject returns the object of an `include` or `exclude` entry
+func includeObject(e interface{}) (expr.ContextData, bool) {
+	switch te := e.(type) {
+	case *orderedObject:
+		return te.ContextData, true
+	case expr.ContextData:
+		return te, true
+	}
+
+	return nil, false
+}
+
+// includeKeys returns the keys of an `include` entry in the order they are defined
+func includeKeys(e interface{}, order keyOrder) []string {
+	if o, ok := e.(*orderedObject); ok {
+		keys := make([]string, 0, len(o.keys))
+		for _, k := range o.keys {
+			keys = append(keys, k.Value)
+		}
+		return keys
+	}
+
+	obj, _ := includeObject(e)
+	return order.keys(obj)
+}
+
+// combine computes the Cartesian product of the vectors, removes the combinations matching an
+// `exclude` entry, and applies the `include` entries
+func combine(vectors []*matrixVector, include, exclude []interface{}, fail func(string, ...interface{}) error) ([]expr.ContextData, error) {
+	if len(vectors) == 0 && len(include) == 0 {
+		return nil, fail("Matrix must define at least one vector")
+	}
+
+	keys := make([]string, 0, len(vectors))
+	product := 1
+	for _, v := range vectors {
+		if len(v.values) == 0 {
+			return nil, fail("Matrix vector '%s' does not contain any values", v.key)
+		}
+		keys = append(keys, v.key)
+
+		// Fail before computing the product, since large vectors would exhaust memory long before
+		// the job limit is checked
+		product *= len(v.values)
+		if product > MaxMatrixJobs {
+			return nil, fail("Strategy produced more than %d jobs", MaxMatrixJobs)
+		}
+	}
+
+	combinations := []expr.ContextData{}
+	if len(vectors) > 0 {
+		combinations = append(combinations, expr.ContextData{})
+	}
+
+	for _, v := range vectors {
+		next := make([]expr.ContextData, 0, len(combinations)*len(v.values))
+		for _, c := range combinations {
+			for _, value := range v.values {
+				nc := copyContext(c)
+				nc[v.key] = value
+				next = append(next, nc)
+			}
+		}
+
+		combinations = next
+	}
+
+	for _, e := range exclude {
+		obj, ok := includeObject(e)
+		if !ok {
+			return nil, fail("Unexpected value '%s'", valueString(e))
+		}
+
+		for k := range obj {
+			if _, ok := findKey(keys, k); !ok {
+				return nil, fail("Matrix exclude key '%s' does not match any key within the matrix", k)
+			}
+		}
+
+		remaining := combinations[:0]
+		for _, c := range combinations {
+			if !partialMatch(obj, c) {
+				remaining = append(remaining, c)
+			}
+		}
+		combinations = remaining
+	}
+
+	// Include entries extend the combinations whose original values they don't overwrite, or are
+	// added as new combinations if there are none. Combinations added by `include` are not
+	// extended by later entries.
+	base := len(combinations)
+	for _, e := range include {
+		obj, ok := includeObject(e)
+		if !ok {
+			return nil, fail("Unexpected value '%s'", valueString(e))
+		}
+
+		matched := false
+		for _, c := range combinations[:base] {
+			if includeMatches(obj, c, keys) {
+				for k, v := range obj {
+					ck, ok := findKey(keys, k)
+					if !ok {
+						ck = k
+					}
+					c[ck] = v
+				}
+				matched = true
+			}
+		}
+
+		if !matched {
+			combinations = append(combinations, copyContext(obj))
+		}
+	}
+
+	if len(combinations) > MaxMatrixJobs {
+		return nil, fail("Strategy produced more than %d jobs", MaxMatrixJobs)
+	}
+
+	return combinations, nil
+}
+
+// findKey returns the vector key matching the given key. Keys are case-insensitive.
+func findKey(keys []string, key string) (string, bool) {
+	for _, k := range keys {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+
+	return "", false
+}
+
+// includeMatches returns true if the include entry doesn't overwrite any of the original values
+// of the combination
+func includeMatches(include expr.ContextData, c expr.ContextData, keys []string) bool {
+	for k, v := range include {
+		ck, ok := findKey(keys, k)
+		if !ok {
+			continue
+		}
+
+		if !reflect.DeepEqual(c[ck], v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// partialMatch returns true if all values of the exclude entry match the values of the
+// combination. Objects match if all their properties in the exclude entry match.
+func partialMatch(exclude interface{}, value interface{}) bool {
+	eo, ok := exclude.(expr.ContextData)
+	if !ok {
+		return reflect.DeepEqual(exclude, value)
+	}
+
+	vo, ok := value.(expr.ContextData)
+	if !ok {
+		return false
+	}
+
+	for k, ev := range eo {
+		_, vv, ok := expr.LookupProperty(vo, k)
+		if !ok || !partialMatch(ev, vv) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// evaluateValue evaluates the expressions embedded in the strings of a value
+func evaluateValue(v interface{}, ctx expr.ContextData, order keyOrder) (interface{}, error) {
+	switch tv := v.(type) {
+	case string:
+		if !strings.Contains(tv, "${{") {
+			return tv, nil
+		}
+		return evaluateMatrixString(tv, ctx, order)
+
+	case expr.ContextData:
+		r := make(expr.ContextData, len(tv))
+		for k, p := range tv {
+			ev, err := evaluateValue(p, ctx, order)
+			if err != nil {
+				return nil, err
+			}
+			r[k] = ev
+		}
+		return r, nil
+
+	case []interface{}:
+		r := make([]interface{}, 0, len(tv))
+		for _, e := range tv {
+			ev, err := evaluateValue(e, ctx, order)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, ev)
+		}
+		return r, nil
+	}
+
+	return v, nil
+}
+
+// rawYAMLValue converts a YAML value into a context value. actionlint doesn't keep the tags of
+// scalars, ParseWorkflow restores them as typedScalar. Other scalars are resolved like plain ones.
+// The key order of objects is recorded in order.
+func rawYAMLValue(v actionlint.RawYAMLValue, order keyOrder) interface{} {
+	switch tv := v.(type) {
+	case *actionlint.RawYAMLObject:
+		obj := make(expr.ContextData, len(tv.Props))
+		keys := make([]string, 0, len(tv.Props))
+		for k, p := range tv.Props {
+			obj[k] = rawYAMLValue(p, order)
+			keys = append(keys, k)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			pi, pj := tv.Props[keys[i]].Pos(), tv.Props[keys[j]].Pos()
+			if pi.Line != pj.Line {
+				return pi.Line < pj.Line
+			}
+			return pi.Col < pj.Col
+		})
+		order.add(keys)
+
+		return obj
+
+	case *actionlint.RawYAMLArray:
+		ar := make([]interface{}, 0, len(tv.Elems))
+		for _, e := range tv.Elems {
+			ar = append(ar, rawYAMLValue(e, order))
+		}
+		return ar
+
+	case *typedScalar:
+		return tv.value
+
+	case *actionlint.RawYAMLString:
+		return scalarValue(&yaml.Node{Kind: yaml.ScalarNode, Value: tv.Value})
+	}
+
+	return nil
+}
+
+// scalarValue converts a YAML scalar into a context value. Only scalars tagged as booleans,
+// numbers, or null aren't strings, so quoted values like "3.10" stay strings.
+func scalarValue(n *yaml.Node) interface{} {
+	switch n.ShortTag() {
+	case "!!bool", "!!int", "!!float", "!!null":
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return n.Value
+		}
+
+		// Expression numbers are floats
+		switch tv := v.(type) {
+		case int:
+			return float64(tv)
+		case int64:
+			return float64(tv)
+		case uint64:
+			return float64(tv)
+		}
+
+		return v
+	}
+
+	return n.Value
+}
+
+// typedScalar is a scalar of a matrix with the value resolved from its YAML tag
+type typedScalar struct {
+	*actionlint.RawYAMLString
+	value interface{}
+}
+
+// typeMatrixScalars replaces the scalars in the matrices of the workflow by typedScalar, with the
+// tags of the scalar nodes of its source at the same positions
+func typeMatrixScalars(w *actionlint.Workflow, doc *yaml.Node) {
+	scalars := map[actionlint.Pos]*yaml.Node{}
+
+	var collect func(n *yaml.Node)
+	collect = func(n *yaml.Node) {
+		if n.Kind == yaml.ScalarNode {
+			scalars[actionlint.Pos{Line: n.Line, Col: n.Column}] = n
+		}
+		for _, c := range n.Content {
+			collect(c)
+		}
+	}
+	collect(doc)
+
+	var typed func(v actionlint.RawYAMLValue) actionlint.RawYAMLValue
+	typed = func(v actionlint.RawYAMLValue) actionlint.RawYAMLValue {
+		switch tv := v.(type) {
+		case *actionlint.RawYAMLObject:
+			for k, p := range tv.Props {
+				tv.Props[k] = typed(p)
+			}
+
+		case *actionlint.RawYAMLArray:
+			for i, e := range tv.Elems {
+				tv.Elems[i] = typed(e)
+			}
+
+		case *actionlint.RawYAMLString:
+			if n, ok := scalars[*tv.Pos()]; ok {
+				return &typedScalar{RawYAMLString: tv, value: scalarValue(n)}
+			}
+		}
+
+		return v
+	}
+
+	combinations := func(cs *actionlint.MatrixCombinations) {
+		if cs == nil {
+			return
+		}
+		for _, c := range cs.Combinations {
+			for _, a := range c.Assigns {
+				a.Value = typed(a.Value)
+			}
+		}
+	}
+
+	for _, j := range w.Jobs {
+		if j.Strategy == nil || j.Strategy.Matrix == nil {
+			continue
+		}
+
+		m := j.Strategy.Matrix
+		for _, r := range m.Rows {
+			for i, v := range r.Values {
+				r.Values[i] = typed(v)
+			}
+		}
+		combinations(m.Include)
+		combinations(m.Exclude)
+	}
+}
+
+// evaluateMatrixString evaluates a value of the matrix like evaluateString. The key order of the
+// objects parsed by `fromJSON` is recorded in order.
+func evaluateMatrixString(s string, ctx expr.ContextData, order keyOrder) (interface{}, error) {
+	v, err := evaluateString(s, ctx, "jobs.<job_id>.strategy")
+	if err != nil {
+		return nil, err
+	}
+
+	exprs, _ := expr.ParseTemplate(s)
+	for _, e := range exprs {
+		actionlint.VisitExprNode(e.Node, func(n, _ actionlint.ExprNode, entering bool) {
+			call, ok := n.(*actionlint.FuncCallNode)
+			if !ok || !entering || !strings.EqualFold(call.Callee, "fromJSON") || len(call.Args) != 1 {
+				return
+			}
+
+			if r, err := expr.Evaluate(call.Args[0], ctx); err == nil {
+				if doc, ok := r.Value.(string); ok {
+					order.addJSON(doc)
+				}
+			}
+		})
+	}
+
+	return v, nil
+}
+
+// keyOrder holds the keys of the objects of a matrix in the order they are defined, which context
+// data doesn't keep. Objects are identified by their set of keys, of objects with the same keys
+// the first one defined determines the order.
+type keyOrder map[string][]string
+
+// add records the order of the keys of an object
+func (o keyOrder) add(keys []string) {
+	id := keyOrderID(keys)
+	if _, ok := o[id]; !ok {
+		o[id] = append([]string(nil), keys...)
+	}
+}
+
+// addJSON records the order of the keys of the objects in a JSON document
+func (o keyOrder) addJSON(doc string) {
+	_ = o.decodeJSON(json.NewDecoder(strings.NewReader(doc)))
+}
+
+// decodeJSON records the order of the keys of the objects in the next JSON value
+func (o keyOrder) decodeJSON(d *json.Decoder) error {
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t {
+	case json.Delim('{'):
+		keys := []string{}
+		for d.More() {
+			k, err := d.Token()
+			if err != nil {
+				return err
+			}
+			keys = append(keys, k.(string))
+
+			if err := o.decodeJSON(d); err != nil {
+				return err
+			}
+		}
+		o.add(keys)
+
+	case json.Delim('['):
+		for d.More() {
+			if err := o.decodeJSON(d); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return nil
+	}
+
+	// Closing delimiter
+	_, err = d.Token()
+	return err
+}
+
+// keys returns the keys of the object in the order they are defined, or sorted if the order isn't
+// known
+func (o keyOrder) keys(obj expr.ContextData) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if ordered, ok := o[keyOrderID(keys)]; ok {
+		return ordered
+	}
+
+	return keys
+}
+
+// keyOrderID identifies an object by its set of keys
+func keyOrderID(keys []string) string {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, "\x00")
+}
@@ -0,0 +1,233 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestExpandMatrix(t *testing.T) {
+	tests := []struct {
+		name    string
+		matrix  string
+		context expr.ContextData
+		want    []expr.ContextData
+		wantErr string
+	}{
+		{
+			name: "product",
+			matrix: `
+        os: [ubuntu, windows]
+        node: [14, 16]`,
+			want: []expr.ContextData{
+				{"os": "ubuntu", "node": float64(14)},
+				{"os": "ubuntu", "node": float64(16)},
+				{"os": "windows", "node": float64(14)},
+				{"os": "windows", "node": float64(16)},
+			},
+		},
+		{
+			name: "scalar tags",
+			matrix: `
+        python: ["3.10", 3.10, '3.9']
+        flag: [true, "true", ~, NaN, 0x10]
+        include:
+          - python: "3.10"
+            nested: { version: "12", count: 12 }`,
+			want: []expr.ContextData{
+				{"python": "3.10", "flag": true, "nested": expr.ContextData{"version": "12", "count": float64(12)}},
+				{"python": "3.10", "flag": "true", "nested": expr.ContextData{"version": "12", "count": float64(12)}},
+				{"python": "3.10", "flag": nil, "nested": expr.ContextData{"version": "12", "count": float64(12)}},
+				{"python": "3.10", "flag": "NaN", "nested": expr.ContextData{"version": "12", "count": float64(12)}},
+				{"python": "3.10", "flag": float64(16), "nested": expr.ContextData{"version": "12", "count": float64(12)}},
+				{"python": 3.1, "flag": true},
+				{"python": 3.1, "flag": "true"},
+				{"python": 3.1, "flag": nil},
+				{"python": 3.1, "flag": "NaN"},
+				{"python": 3.1, "flag": float64(16)},
+				{"python": "3.9", "flag": true},
+				{"python": "3.9", "flag": "true"},
+				{"python": "3.9", "flag": nil},
+				{"python": "3.9", "flag": "NaN"},
+				{"python": "3.9", "flag": float64(16)},
+			},
+		},
+		{
+			name: "exclude partial match",
+			matrix: `
+        os: [ubuntu, windows]
+        node: [14, 16]
+        exclude:
+          - os: windows`,
+			want: []expr.ContextData{
+				{"os": "ubuntu", "node": float64(14)},
+				{"os": "ubuntu", "node": float64(16)},
+			},
+		},
+		{
+			name: "exclude nested object",
+			matrix: `
+        env:
+          - { os: ubuntu, version: 22 }
+          - { os: windows, version: 2022 }
+        exclude:
+          - env: { os: windows }`,
+			want: []expr.ContextData{
+				{"env": expr.ContextData{"os": "ubuntu", "version": float64(22)}},
+			},
+		},
+		{
+			name: "include extends or appends",
+			matrix: `
+        fruit: [apple, pear]
+        animal: [cat, dog]
+        include:
+          - color: green
+          - color: pink
+            animal: cat
+          - fruit: apple
+            shape: circle
+          - fruit: banana
+          - fruit: banana
+            animal: cat`,
+			want: []expr.ContextData{
+				{"fruit": "apple", "animal": "cat", "color": "pink", "shape": "circle"},
+				{"fruit": "apple", "animal": "dog", "color": "green", "shape": "circle"},
+				{"fruit": "pear", "animal": "cat", "color": "pink"},
+				{"fruit": "pear", "animal": "dog", "color": "green"},
+				{"fruit": "banana"},
+				{"fruit": "banana", "animal": "cat"},
+			},
+		},
+		{
+			name: "include only",
+			matrix: `
+        include:
+          - site: production
+          - site: staging`,
+			want: []expr.ContextData{
+				{"site": "production"},
+				{"site": "staging"},
+			},
+		},
+		{
+			name:   "fromJSON",
+			matrix: "${{ fromJSON(needs.setup.outputs.matrix) }}",
+			context: expr.ContextData{"needs": expr.ContextData{"setup": expr.ContextData{"outputs": expr.ContextData{
+				"matrix": `{"os": ["ubuntu"], "include": [{"os": "macos"}]}`,
+			}}}},
+			want: []expr.ContextData{
+				{"os": "ubuntu"},
+				{"os": "macos"},
+			},
+		},
+		{
+			name: "expression vector",
+			matrix: `
+        os: ${{ fromJSON(inputs.os) }}
+        node: ["${{ inputs.node }}"]`,
+			context: expr.ContextData{"inputs": expr.ContextData{"os": `["ubuntu"]`, "node": "18"}},
+			want: []expr.ContextData{
+				{"os": "ubuntu", "node": "18"},
+			},
+		},
+		{
+			name: "empty vector",
+			matrix: `
+        os: ${{ fromJSON('[]') }}`,
+			wantErr: "Error when evaluating 'strategy' for job 'build'. (Line: 6, Col: 7): Matrix vector 'os' does not contain any values",
+		},
+		{
+			name: "unknown exclude key",
+			matrix: `
+        os: [ubuntu]
+        exclude:
+          - node: 14`,
+			wantErr: "Error when evaluating 'strategy' for job 'build'. (Line: 6, Col: 7): Matrix exclude key 'node' does not match any key within the matrix",
+		},
+		{
+			name: "job limit",
+			matrix: `
+        a: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17]
+        b: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16]`,
+			wantErr: "Error when evaluating 'strategy' for job 'build'. (Line: 6, Col: 7): Strategy produced more than 256 jobs",
+		},
+		{
+			name: "job limit before product",
+			matrix: `
+        a: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        b: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        c: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        d: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        e: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        f: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        g: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        h: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        i: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+        j: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]`,
+			wantErr: "Error when evaluating 'strategy' for job 'build'. (Line: 6, Col: 7): Strategy produced more than 256 jobs",
+		},
+		{
+			name: "job limit after include",
+			matrix: `
+        a: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16]
+        b: [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16]
+        include:
+          - a: 17`,
+			wantErr: "Error when evaluating 'strategy' for job 'build'. (Line: 6, Col: 7): Strategy produced more than 256 jobs",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    strategy:
+      matrix: `+tt.matrix+`
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+			m, known, err := ExpandMatrix(w.Jobs["build"], tt.context)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("ExpandMatrix() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandMatrix() error = %v", err)
+			}
+			if !known {
+				t.Fatalf("ExpandMatrix() is unknown")
+			}
+			if !reflect.DeepEqual(m.Combinations, tt.want) {
+				t.Errorf("ExpandMatrix() = %v, want %v", m.Combinations, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandMatrix_Unknown(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    strategy:
+      matrix:
+        os: ${{ fromJSON(needs.setup.outputs.os) }}
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+	_, known, err := ExpandMatrix(w.Jobs["build"], expr.ContextData{
+		"needs": expr.ContextData{"setup": expr.ContextData{"outputs": expr.Unknown}},
+	})
+	if err != nil || known {
+		t.Errorf("ExpandMatrix() = %v, %v, want unknown", known, err)
+	}
+}
@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// JobDisplayName returns the name GitHub displays for a run of the job. Matrix is the matrix
+// combination of the run and m the expanded matrix of the job, nil for jobs without a matrix. ctx
+// are the contexts available to `jobs.<job_id>.name`.
+//
+// Runs of matrix jobs are named like `build (ubuntu-latest, 18)`, unless the `name:` of the job
+// references the `matrix` context, in which case the evaluated name is used verbatim. Values of
+// objects in matrix combinations are listed in the order their keys are defined. actionlint stores
+// job IDs in lower case, so jobs without a name are named by their lower case ID.
+func JobDisplayName(job *actionlint.Job, matrix expr.ContextData, m *Matrix, ctx expr.ContextData) (string, error) {
+	name := job.ID.Value
+
+	if job.Name != nil {
+		rctx := copyContext(ctx)
+		if matrix != nil {
+			rctx["matrix"] = matrix
+		}
+
+		v, err := evaluateString(job.Name.Value, rctx, "jobs.<job_id>.name")
+		if err != nil {
+			return "", err
+		}
+
+		// Names depending on values only known at runtime are shown as they are written
+		name = job.Name.Value
+		if v != expr.Unknown {
+			name = expr.NewEvaluationResult(v).CoerceString()
+		}
+
+		if referencesMatrix(job.Name.Value) {
+			return name, nil
+		}
+	}
+
+	if m == nil || len(matrix) == 0 {
+		return name, nil
+	}
+
+	values := []string{}
+	for _, k := range m.Keys {
+		if _, v, ok := expr.LookupProperty(matrix, k); ok {
+			values = appendMatrixValues(values, v, m.order)
+		}
+	}
+
+	return name + " (" + strings.Join(values, ", ") + ")", nil
+}
+
+// CalledJobDisplayName returns the name GitHub displays for a job of a called reusable workflow,
+// like `call-build / build`
+func CalledJobDisplayName(caller string, callee string) string {
+	return caller + " / " + callee
+}
+
+// referencesMatrix returns true if any expression embedded in the string accesses the `matrix`
+// context
+func referencesMatrix(s string) bool {
+	exprs, err := expr.ParseTemplate(s)
+	if err != nil {
+		return false
+	}
+
+	found := false
+	for _, e := range exprs {
+		actionlint.VisitExprNode(e.Node, func(n, _ actionlint.ExprNode, entering bool) {
+			if v, ok := n.(*actionlint.VariableNode); ok && entering && v.Name == "matrix" {
+				found = true
+			}
+		})
+	}
+
+	return found
+}
+
+// appendMatrixValues appends the string representation of a matrix value. Objects and arrays
+// are flattened, the values of objects are listed in the order of their keys in order.
+func appendMatrixValues(values []string, v interface{}, order keyOrder) []string {
+	switch tv := v.(type) {
+	case expr.ContextData:
+		for _, k := range order.keys(tv) {
+			values = appendMatrixValues(values, tv[k], order)
+		}
+		return values
+
+	case []interface{}:
+		for _, e := range tv {
+			values = appendMatrixValues(values, e, order)
+		}
+		return values
+	}
+
+	return append(values, expr.NewEvaluationResult(v).CoerceString())
+}
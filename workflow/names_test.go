@@ -0,0 +1,190 @@
+package workflow
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestJobDisplayName(t *testing.T) {
+	tests := []struct {
+		name string
+		job  string
+		want []string
+	}{
+		{
+			name: "no matrix",
+			job: `
+    name: Build ${{ github.event_name }}`,
+			want: []string{"Build push"},
+		},
+		{
+			name: "matrix values",
+			job: `
+    strategy:
+      matrix:
+        os: [ubuntu-latest]
+        node: [18, 20]
+        include:
+          - node: 20
+            experimental: true`,
+			want: []string{"build (ubuntu-latest, 18)", "build (ubuntu-latest, 20, true)"},
+		},
+		{
+			name: "name without matrix reference",
+			job: `
+    name: Build
+    strategy:
+      matrix:
+        os: [ubuntu-latest]`,
+			want: []string{"Build (ubuntu-latest)"},
+		},
+		{
+			name: "name with matrix reference",
+			job: `
+    name: Build on ${{ matrix.os }}
+    strategy:
+      matrix:
+        os: [ubuntu-latest]
+        node: [18]`,
+			want: []string{"Build on ubuntu-latest"},
+		},
+		{
+			name: "quoted version",
+			job: `
+    strategy:
+      matrix:
+        python: ["3.10", 3.10]`,
+			want: []string{"build (3.10)", "build (3.1)"},
+		},
+		{
+			name: "object values",
+			job: `
+    strategy:
+      matrix:
+        target:
+          - { os: linux, arch: amd64 }`,
+			want: []string{"build (linux, amd64)"},
+		},
+		{
+			name: "JSON matrix",
+			job: `
+    strategy:
+      matrix: ${{ fromJSON('{"target":[{"os":"linux","arch":"amd64"}],"os":["ubuntu"]}') }}`,
+			want: []string{"build (linux, amd64, ubuntu)"},
+		},
+		{
+			name: "JSON object values",
+			job: `
+    strategy:
+      matrix:
+        target: ${{ fromJSON('[{"version":"12","distro":"debian"}]') }}`,
+			want: []string{"build (12, debian)"},
+		},
+		{
+			name: "unknown name",
+			job: `
+    name: Build ${{ needs.setup.outputs.name }}`,
+			want: []string{"Build ${{ needs.setup.outputs.name }}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseWorkflow(t, `
+on: push
+jobs:
+  build:`+tt.job+`
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+			job := w.Jobs["build"]
+			ctx := expr.ContextData{
+				"github": expr.ContextData{"event_name": "push"},
+				"needs":  expr.ContextData{"setup": expr.ContextData{"outputs": expr.Unknown}},
+			}
+
+			m, _, err := ExpandMatrix(job, ctx)
+			if err != nil {
+				t.Fatalf("ExpandMatrix() error = %v", err)
+			}
+
+			got := []string{}
+			if m == nil {
+				name, err := JobDisplayName(job, nil, nil, ctx)
+				if err != nil {
+					t.Fatalf("JobDisplayName() error = %v", err)
+				}
+				got = append(got, name)
+			} else {
+				for _, c := range m.Combinations {
+					name, err := JobDisplayName(job, c, m, ctx)
+					if err != nil {
+						t.Fatalf("JobDisplayName() error = %v", err)
+					}
+					got = append(got, name)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JobDisplayName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulate_CalledJobDisplayName(t *testing.T) {
+	dir := testRepo(t, map[string]string{
+		"ci.yml": `
+on: push
+jobs:
+  call:
+    name: call (ubuntu)
+    uses: ./.github/workflows/build.yml
+`,
+		"build.yml": `
+on: workflow_call
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+  test:
+    strategy:
+      matrix:
+        node: [18, 20]
+    runs-on: ubuntu-latest
+    steps:
+      - run: make test
+`,
+	})
+
+	w, err := LoadWorkflow(filepath.Join(dir, ".github", "workflows", "ci.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := Simulate(w, &Event{Name: "push", Payload: expr.ContextData{}}, Options{Dir: dir})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	called := plan.Job("call").Runs[0].Workflow
+	if called == nil {
+		t.Fatalf("Simulate() didn't simulate the called workflow")
+	}
+
+	got := []string{}
+	for _, jp := range called.Jobs {
+		for _, r := range jp.Runs {
+			got = append(got, r.Name)
+		}
+	}
+
+	want := []string{"call (ubuntu) / build", "call (ubuntu) / test (18)", "call (ubuntu) / test (20)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Simulate() called job names = %v, want %v", got, want)
+	}
+}
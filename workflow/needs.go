@@ -0,0 +1,154 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// Job results, as available in `needs.<job_id>.result`
+const (
+	JobSuccess   = "success"
+	JobFailure   = "failure"
+	JobCancelled = "cancelled"
+	JobSkipped   = "skipped"
+)
+
+// JobResult is the result of a completed job as seen by the jobs that need it
+type JobResult struct {
+	// Result is one of the Job* constants, or expr.Unknown
+	Result interface{}
+
+	// Outputs are the outputs of the job, or expr.Unknown
+	Outputs interface{}
+}
+
+// NeedsContext returns the `needs` context of the job. Like on GitHub it only contains the direct
+// dependencies of the job. Results are keyed by job ID in lower case, dependencies without a
+// result are unknown.
+func NeedsContext(job *actionlint.Job, results map[string]*JobResult) expr.ContextData {
+	needs := expr.ContextData{}
+
+	for _, n := range job.Needs {
+		id := strings.ToLower(n.Value)
+
+		r, ok := results[id]
+		if !ok {
+			needs[id] = expr.ContextData{"result": expr.Unknown, "outputs": expr.Unknown}
+			continue
+		}
+
+		outputs := r.Outputs
+		if outputs == nil {
+			outputs = expr.ContextData{}
+		}
+		needs[id] = expr.ContextData{"result": r.Result, "outputs": outputs}
+	}
+
+	return needs
+}
+
+// NeedsStatus returns the status checked by the status functions in the condition of the job,
+// and the reason for a status other than success. Unlike the `needs` context, the status depends
+// on all jobs the job transitively needs: if any of them fails or is skipped, jobs without a
+// status function in their condition are skipped, even if the direct dependencies succeed.
+func NeedsStatus(w *actionlint.Workflow, job *actionlint.Job, results map[string]*JobResult) (expr.Status, string) {
+	status, reason := expr.StatusSuccess, ""
+
+	for _, id := range ancestors(w, job) {
+		var result interface{} = expr.Unknown
+		if r, ok := results[id]; ok {
+			result = r.Result
+		}
+
+		switch result {
+		case JobFailure:
+			// A failed job takes precedence over other results
+			return expr.StatusFailure, fmt.Sprintf("needed job %q fails", id)
+
+		case JobCancelled:
+			if status != expr.StatusCancelled {
+				status, reason = expr.StatusCancelled, fmt.Sprintf("needed job %q is cancelled", id)
+			}
+
+		case JobSkipped:
+			if status != expr.StatusCancelled && status != expr.StatusSkipped {
+				status, reason = expr.StatusSkipped, fmt.Sprintf("needed job %q is skipped", id)
+			}
+
+		case JobSuccess:
+
+		default:
+			if status == expr.StatusSuccess {
+				status, reason = expr.StatusUnknown, fmt.Sprintf("needed job %q may not run", id)
+			}
+		}
+	}
+
+	return status, reason
+}
+
+// ancestors returns the IDs of the jobs the job transitively needs in lower case, direct
+// dependencies first and each level ordered by ID
+func ancestors(w *actionlint.Workflow, job *actionlint.Job) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+
+	level := []*actionlint.Job{job}
+	for len(level) > 0 {
+		next := []string{}
+		for _, j := range level {
+			for _, n := range j.Needs {
+				id := strings.ToLower(n.Value)
+				if !seen[id] {
+					seen[id] = true
+					next = append(next, id)
+				}
+			}
+		}
+		sort.Strings(next)
+
+		ids = append(ids, next...)
+
+		level = level[:0]
+		for _, id := range next {
+			if j, ok := w.Jobs[id]; ok {
+				level = append(level, j)
+			}
+		}
+	}
+
+	return ids
+}
+
+// jobResult returns the result of a simulated job. Jobs that run succeed unless one of their
+// runs fails, see mergeOutputs for the outputs of matrix jobs.
+func jobResult(jp *JobPlan) *JobResult {
+	switch jp.Status {
+	case StatusSkipped:
+		return &JobResult{Result: JobSkipped, Outputs: expr.ContextData{}}
+
+	case StatusError:
+		return &JobResult{Result: JobFailure, Outputs: expr.ContextData{}}
+
+	case StatusUndecidable:
+		return &JobResult{Result: expr.Unknown, Outputs: expr.Unknown}
+	}
+
+	var result interface{} = JobSuccess
+	for _, r := range jp.Runs {
+		switch {
+		case r.Result == JobFailure:
+			result = JobFailure
+		case r.Result == JobCancelled && result != JobFailure:
+			result = JobCancelled
+		case r.Result != JobSuccess && result == JobSuccess:
+			result = expr.Unknown
+		}
+	}
+
+	return &JobResult{Result: result, Outputs: mergeOutputs(jp.Runs)}
+}
@@ -0,0 +1,151 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestNeeds(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  a:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+  b:
+    needs: a
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+  c:
+    needs: [b]
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+  d:
+    needs: [a, c]
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+	tests := []struct {
+		name       string
+		job        string
+		results    map[string]*JobResult
+		wantNeeds  expr.ContextData
+		wantStatus expr.Status
+		wantReason string
+	}{
+		{
+			name:       "success",
+			job:        "c",
+			results:    map[string]*JobResult{"a": {JobSuccess, expr.ContextData{}}, "b": {JobSuccess, expr.ContextData{"v": "1"}}},
+			wantNeeds:  expr.ContextData{"b": expr.ContextData{"result": JobSuccess, "outputs": expr.ContextData{"v": "1"}}},
+			wantStatus: expr.StatusSuccess,
+		},
+		{
+			name:       "transitive failure",
+			job:        "c",
+			results:    map[string]*JobResult{"a": {JobFailure, expr.ContextData{}}, "b": {JobSuccess, expr.ContextData{}}},
+			wantNeeds:  expr.ContextData{"b": expr.ContextData{"result": JobSuccess, "outputs": expr.ContextData{}}},
+			wantStatus: expr.StatusFailure,
+			wantReason: `needed job "a" fails`,
+		},
+		{
+			name:       "transitive skip",
+			job:        "d",
+			results:    map[string]*JobResult{"a": {JobSuccess, expr.ContextData{}}, "b": {JobSkipped, expr.ContextData{}}, "c": {JobSuccess, expr.ContextData{}}},
+			wantNeeds:  expr.ContextData{"a": expr.ContextData{"result": JobSuccess, "outputs": expr.ContextData{}}, "c": expr.ContextData{"result": JobSuccess, "outputs": expr.ContextData{}}},
+			wantStatus: expr.StatusSkipped,
+			wantReason: `needed job "b" is skipped`,
+		},
+		{
+			name:       "unknown",
+			job:        "b",
+			results:    map[string]*JobResult{},
+			wantNeeds:  expr.ContextData{"a": expr.ContextData{"result": expr.Unknown, "outputs": expr.Unknown}},
+			wantStatus: expr.StatusUnknown,
+			wantReason: `needed job "a" may not run`,
+		},
+		{
+			name:       "no needs",
+			job:        "a",
+			results:    map[string]*JobResult{},
+			wantNeeds:  expr.ContextData{},
+			wantStatus: expr.StatusSuccess,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := w.Jobs[tt.job]
+
+			if got := NeedsContext(job, tt.results); !reflect.DeepEqual(got, tt.wantNeeds) {
+				t.Errorf("NeedsContext() = %v, want %v", got, tt.wantNeeds)
+			}
+
+			status, reason := NeedsStatus(w, job, tt.results)
+			if status != tt.wantStatus || reason != tt.wantReason {
+				t.Errorf("NeedsStatus() = %v, %q, want %v, %q", status, reason, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSimulate_JobFailure(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - if: secrets.TOKEN != ''
+        run: make test
+  report:
+    needs: test
+    if: always()
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo ${{ needs.test.result }}
+  deploy:
+    needs: report
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./deploy.sh
+  notify:
+    needs: report
+    if: needs.report.result == 'success' && failure()
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo notify
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	tests := []struct {
+		job    string
+		status Status
+		result interface{}
+	}{
+		{"test", StatusRun, JobFailure},
+		{"report", StatusRun, JobSuccess},
+		{"deploy", StatusSkipped, JobSkipped},
+		{"notify", StatusRun, JobSuccess},
+	}
+	for _, tt := range tests {
+		j := plan.Job(tt.job)
+		if j.Status != tt.status || j.Result.Result != tt.result {
+			t.Errorf("Simulate() job %s = %v, %v, want %v, %v", tt.job, j.Status, j.Result.Result, tt.status, tt.result)
+		}
+	}
+
+	if r := plan.Job("deploy").Reason; r != `needed job "test" fails` {
+		t.Errorf("Simulate() reason = %q", r)
+	}
+}
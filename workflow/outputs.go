@@ -0,0 +1,77 @@
+package workflow
+
+import (
+	"fmt"
+	"sort"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// JobOutputs evaluates the `outputs:` of a run of the job when it completes. Ctx are the contexts
+// of the run after its last step, including the `steps` context. Outputs must evaluate to strings,
+// other primitive values are converted and objects or arrays are an error. Like on GitHub,
+// outputs derived from secrets are dropped. Unknown values stay unknown.
+func JobOutputs(job *actionlint.Job, ctx expr.ContextData) (expr.ContextData, error) {
+	names := make([]string, 0, len(job.Outputs))
+	for name := range job.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	outputs := expr.ContextData{}
+	for _, name := range names {
+		o := job.Outputs[name]
+		if o.Value == nil {
+			outputs[name] = ""
+			continue
+		}
+
+		v, sensitive, err := evaluateTemplate(o.Value.Value, ctx, "jobs.<job_id>.outputs.<output_id>")
+		if err != nil {
+			return nil, fmt.Errorf("output %q of job %q: %v", name, job.ID.Value, err)
+		}
+
+		if sensitive {
+			continue
+		}
+
+		if v == expr.Unknown {
+			outputs[name] = expr.Unknown
+			continue
+		}
+
+		r := expr.NewEvaluationResult(v)
+		if !r.Primitive() {
+			return nil, fmt.Errorf("output %q of job %q must evaluate to a string, got %s", name, job.ID.Value, r.Type.String())
+		}
+
+		outputs[name] = r.CoerceString()
+	}
+
+	return outputs, nil
+}
+
+// mergeOutputs merges the outputs of the runs of a matrix job. On GitHub the run finishing last
+// wins, here runs are assumed to finish in the order of the matrix: values of later runs override
+// the ones of earlier runs, except for empty values. The result is expr.Unknown if the outputs of
+// any run are unknown.
+func mergeOutputs(runs []*JobRunPlan) interface{} {
+	merged := expr.ContextData{}
+
+	for _, r := range runs {
+		outputs, ok := r.Outputs.(expr.ContextData)
+		if !ok {
+			return expr.Unknown
+		}
+
+		for k, v := range outputs {
+			if _, ok := merged[k]; ok && v == "" {
+				continue
+			}
+			merged[k] = v
+		}
+	}
+
+	return merged
+}
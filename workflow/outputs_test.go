@@ -0,0 +1,106 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestJobOutputs(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    outputs:
+      version: ${{ steps.version.outputs.version }}
+      os: ${{ matrix.os }}
+      count: ${{ strategy.job-total }}
+      tag: v${{ steps.version.outputs.version }}
+      token: ${{ secrets.TOKEN }}
+      masked: ${{ format('{0}-{1}', matrix.os, secrets.TOKEN) }}
+      literal: fixed
+    steps:
+      - id: version
+        run: echo "version=1.2.3" >> $GITHUB_OUTPUT
+  invalid:
+    runs-on: ubuntu-latest
+    outputs:
+      matrix: ${{ matrix }}
+    steps:
+      - run: echo
+`)
+
+	ctx := expr.ContextData{
+		"matrix":   expr.ContextData{"os": "linux"},
+		"strategy": expr.ContextData{"job-total": float64(2)},
+		"secrets":  expr.ContextData{"token": "secret"},
+		"steps":    expr.ContextData{"version": expr.ContextData{"outputs": expr.ContextData{"version": "1.2.3"}}},
+	}
+
+	got, err := JobOutputs(w.Jobs["build"], ctx)
+	if err != nil {
+		t.Fatalf("JobOutputs() error = %v", err)
+	}
+
+	want := expr.ContextData{"version": "1.2.3", "os": "linux", "count": "2", "tag": "v1.2.3", "literal": "fixed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("JobOutputs() = %v, want %v", got, want)
+	}
+
+	if _, err := JobOutputs(w.Jobs["invalid"], ctx); err == nil {
+		t.Errorf("JobOutputs() error = nil, want error for object output")
+	}
+}
+
+func TestSimulate_Outputs(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        os: [linux, windows]
+        include:
+          - os: linux
+            artifact: app.tar.gz
+    outputs:
+      os: ${{ matrix.os }}
+      artifact: ${{ matrix.artifact }}
+      version: ${{ steps.version.outputs.version }}
+    steps:
+      - id: version
+        run: echo "version=1.2.3" >> $GITHUB_OUTPUT
+  deploy:
+    needs: build
+    if: needs.build.outputs.os == 'windows' && needs.build.outputs.artifact == 'app.tar.gz'
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./deploy.sh
+  release:
+    needs: build
+    if: needs.build.outputs.version != ''
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./release.sh
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := expr.ContextData{"os": "windows", "artifact": "app.tar.gz", "version": expr.Unknown}
+	if got := plan.Job("build").Result.Outputs; !reflect.DeepEqual(got, want) {
+		t.Errorf("Simulate() outputs = %v, want %v", got, want)
+	}
+
+	if s := plan.Job("deploy").Status; s != StatusRun {
+		t.Errorf("Simulate() deploy = %v, want run", s)
+	}
+	if s := plan.Job("release").Status; s != StatusUndecidable {
+		t.Errorf("Simulate() release = %v, want undecidable", s)
+	}
+}
@@ -0,0 +1,214 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+// Status is the simulated outcome for a job or step
+type Status int
+
+const (
+	// StatusRun is used for jobs and steps that would run
+	StatusRun Status = iota
+
+	// StatusSkipped is used for jobs and steps that would be skipped
+	StatusSkipped
+
+	// StatusUndecidable is used when it can only be decided at runtime whether a job or step runs,
+	// e.g. because its condition depends on step outputs.
+	StatusUndecidable
+
+	// StatusError is used for jobs that fail before running any step, e.g. because of an invalid
+	// matrix, and for jobs and steps whose condition can't be evaluated, e.g. because it uses a
+	// context that is not available
+	StatusError
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusRun:
+		return "run"
+	case StatusSkipped:
+		return "skipped"
+	case StatusError:
+		return "error"
+	default:
+		return "undecidable"
+	}
+}
+
+// Plan lists the jobs of a workflow in the order they would run
+type Plan struct {
+	Jobs []*JobPlan
+}
+
+// Job returns the plan of the job with the given ID, or nil
+func (p *Plan) Job(id string) *JobPlan {
+	for _, j := range p.Jobs {
+		if strings.EqualFold(j.ID, id) {
+			return j
+		}
+	}
+
+	return nil
+}
+
+func (p *Plan) String() string {
+	var b strings.Builder
+	p.write(&b, "")
+
+	return b.String()
+}
+
+// write renders the plan with every line indented, plans of called workflows are nested below
+// their runs
+func (p *Plan) write(b *strings.Builder, prefix string) {
+	for _, j := range p.Jobs {
+		fmt.Fprintf(b, "%s%s: %s%s\n", prefix, j.ID, j.Status, reasonSuffix(j.Reason))
+
+		for _, r := range j.Runs {
+			indent := prefix + "  "
+			if r.Matrix != nil {
+				fmt.Fprintf(b, "%s  %s %s\n", prefix, r.Name, matrixString(r.Matrix))
+				indent = prefix + "    "
+			}
+
+			writeSteps(b, r.Steps, indent)
+
+			if r.Workflow != nil {
+				r.Workflow.write(b, indent)
+			}
+		}
+	}
+}
+
+// writeSteps renders the steps, steps of composite actions are nested below the step running them
+func writeSteps(b *strings.Builder, steps []*StepPlan, indent string) {
+	for _, s := range steps {
+		fmt.Fprintf(b, "%s[%d] %s: %s%s\n", indent, s.Index, s.Name, s.Status, reasonSuffix(s.Reason))
+		for _, w := range s.Warnings {
+			fmt.Fprintf(b, "%s  warning: %s\n", indent, w)
+		}
+		writeSteps(b, s.Steps, indent+"  ")
+	}
+}
+
+func reasonSuffix(reason string) string {
+	if reason == "" {
+		return ""
+	}
+
+	return " (" + reason + ")"
+}
+
+// matrixString renders the matrix values ordered by key, like `{node: 14, os: ubuntu-latest}`
+func matrixString(m expr.ContextData) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+valueString(m[k]))
+	}
+
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func valueString(v interface{}) string {
+	if v == expr.Unknown {
+		return "<unknown>"
+	}
+
+	if s, ok := v.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
+
+// JobPlan is the simulated outcome of a job
+type JobPlan struct {
+	ID   string
+	Name string
+
+	Status Status
+
+	// Reason explains why the job is skipped, undecidable, or fails
+	Reason string
+
+	// Runs contains a run for every matrix combination, or a single run for jobs without a matrix.
+	// It's empty for skipped and failed jobs.
+	Runs []*JobRunPlan
+
+	// Result is the result of the job as seen by the jobs that need it, see JobResult
+	Result *JobResult
+}
+
+// JobRunPlan is the simulated outcome of a single run of a job
+type JobRunPlan struct {
+	// Name is the name GitHub displays for the run, like `build (ubuntu-latest, 18)`
+	Name string
+
+	// Matrix are the matrix values of the run, nil for jobs without a matrix
+	Matrix expr.ContextData
+
+	Steps []*StepPlan
+
+	// Result is the result of the run, one of the Job* constants or expr.Unknown. Runs fail if a
+	// step fails, see StepsContext, or if the outputs can't be evaluated.
+	Result interface{}
+
+	// Reason explains why the outputs of the run can't be evaluated
+	Reason string
+
+	// Outputs are the outputs of the run, see JobOutputs, or expr.Unknown. For runs calling a
+	// reusable workflow they are the outputs of the called workflow, see CallOutputs.
+	Outputs interface{}
+
+	// Workflow is the plan of the local reusable workflow called by the run, see Options.Dir
+	Workflow *Plan
+}
+
+// StepPlan is the simulated outcome of a step
+type StepPlan struct {
+	Index int
+
+	// ID is the `id` of the step, or the ID generated by the runner for steps without one, see
+	// StepIDs
+	ID   string
+	Name string
+
+	Status Status
+
+	// Reason explains why the step is skipped, undecidable, or fails
+	Reason string
+
+	// Env is the env context of the step, see StepEnv, and the `INPUT_*` variables of actions
+	// with known metadata that aren't composite, see InputEnv. It's expr.Unknown if the variables
+	// depend on values only known at runtime.
+	Env interface{}
+
+	// Steps are the steps of the composite action run by the step, see Options.Dir
+	Steps []*StepPlan
+
+	// Inputs are the inputs of the action run by the step, see ActionInputs. Nil if the metadata
+	// of the action isn't available.
+	Inputs expr.ContextData
+
+	// Warnings report problems that don't fail the step, like inputs the action doesn't declare,
+	// see CheckWith and CheckOutputReferences
+	Warnings []string
+}
@@ -0,0 +1,397 @@
+package workflow
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// DefaultMaxDepth is the depth of cascades followed by Route if no depth is given. GitHub doesn't
+// run `workflow_run` chains of more than three levels.
+const DefaultMaxDepth = 3
+
+// RouteOptions configures optional behavior of Route
+type RouteOptions struct {
+	// MaxDepth is the maximum number of levels of the cascade. Defaults to DefaultMaxDepth.
+	MaxDepth int
+
+	// Options are the options of the simulation of every workflow
+	Options
+}
+
+// RouteNode is a workflow started in a cascade of workflows
+type RouteNode struct {
+	File *File
+
+	// Via is how the workflow was started: `event` for the initial event, `workflow_run`,
+	// `workflow_call`, `repository_dispatch`, or `workflow_dispatch`
+	Via   string
+	Event *Event
+
+	// Match is the result of matching the event against the triggers of the workflow. Workflows
+	// started via `workflow_call` always match.
+	Match *TriggerMatch
+
+	// Cycle is set if the workflow already started earlier in the same branch of the cascade. Its
+	// children are not followed.
+	Cycle bool
+
+	// Truncated is set if workflows started by the workflow are left out because the maximum
+	// depth is reached
+	Truncated bool
+
+	Children []*RouteNode
+}
+
+// WorkflowName returns the name of the workflow, as used by `workflow_run` filters. Workflows
+// without a name are named by their path.
+func (f *File) WorkflowName() string {
+	if f.Workflow.Name != nil {
+		return f.Workflow.Name.Value
+	}
+
+	return ".github/workflows/" + f.Path
+}
+
+// Route returns the workflows started by the event, and follows the cascade of workflows they
+// start in turn: `workflow_run` events fired by the started workflows, reusable workflows they
+// call, and `repository_dispatch` or `workflow_dispatch` events sent by known steps like
+// `gh workflow run` or peter-evans/repository-dispatch. Workflows are assumed to succeed. Files
+// that failed to load are left out.
+func Route(files []*File, event *Event, opts RouteOptions) ([]*RouteNode, error) {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = DefaultMaxDepth
+	}
+
+	r := &router{opts: opts}
+	for _, f := range files {
+		if f.Err == nil {
+			r.files = append(r.files, f)
+		}
+	}
+
+	nodes, _, err := r.route(event, "event", nil, nil, 1)
+
+	return nodes, err
+}
+
+// router holds the state of a single routing
+type router struct {
+	files []*File
+	opts  RouteOptions
+}
+
+// route returns the nodes of the workflows started by the event at the given depth. Targets
+// restricts the workflows that can be started, e.g. for `workflow_dispatch`. Ancestors are the
+// workflows started earlier in the same branch of the cascade. Truncated is set if workflows
+// would start beyond the maximum depth.
+func (r *router) route(event *Event, via string, targets []*File, ancestors []*File, depth int) (nodes []*RouteNode, truncated bool, err error) {
+	if targets == nil {
+		targets = r.files
+	}
+
+	nodes = []*RouteNode{}
+	for _, f := range targets {
+		m, err := MatchTrigger(f.Workflow, event)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %v", f.Path, err)
+		}
+
+		if m.Status == StatusSkipped {
+			continue
+		}
+
+		if depth > r.opts.MaxDepth {
+			truncated = true
+			continue
+		}
+
+		n, err := r.start(f, event, via, m, ancestors, depth)
+		if err != nil {
+			return nil, false, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, truncated, nil
+}
+
+// start returns the node for a started workflow and follows the workflows it starts
+func (r *router) start(f *File, event *Event, via string, m *TriggerMatch, ancestors []*File, depth int) (*RouteNode, error) {
+	n := &RouteNode{File: f, Via: via, Event: event, Match: m}
+
+	for _, a := range ancestors {
+		if a == f {
+			n.Cycle = true
+			return n, nil
+		}
+	}
+
+	ancestors = append(append([]*File{}, ancestors...), f)
+
+	plan, err := Simulate(f.Workflow, event, r.opts.Options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", f.Path, err)
+	}
+
+	for _, jp := range plan.Jobs {
+		if jp.Status == StatusSkipped || jp.Status == StatusError {
+			continue
+		}
+
+		job := f.Workflow.Jobs[jp.ID]
+
+		// Reusable workflows called by the job
+		if job.WorkflowCall != nil && job.WorkflowCall.Uses != nil {
+			if callee := r.localWorkflow(job.WorkflowCall.Uses.Value); callee != nil && depth+1 > r.opts.MaxDepth {
+				n.Truncated = true
+			} else if callee != nil {
+				ce := &Event{Name: "workflow_call", Payload: event.Payload, ChangedFiles: event.ChangedFiles}
+				c, err := r.start(callee, ce, "workflow_call", &TriggerMatch{Status: jp.Status}, ancestors, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				n.Children = append(n.Children, c)
+			}
+		}
+
+		// Dispatches sent by steps that may run in any run of a matrix
+		sends := make([]bool, len(job.Steps))
+		for _, run := range jp.Runs {
+			for _, sp := range run.Steps {
+				if sp.Status != StatusSkipped {
+					sends[sp.Index] = true
+				}
+			}
+		}
+
+		for idx, step := range job.Steps {
+			if !sends[idx] {
+				continue
+			}
+
+			for _, d := range dispatches(step) {
+				var targets []*File
+				if d.workflow != "" {
+					targets = r.findWorkflows(d.workflow)
+				}
+
+				children, truncated, err := r.route(d.event, d.event.Name, targets, ancestors, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				n.Children = append(n.Children, children...)
+				n.Truncated = n.Truncated || truncated
+			}
+		}
+	}
+
+	// Called workflows are part of the run of their caller and don't fire workflow_run events
+	if via == "workflow_call" {
+		return n, nil
+	}
+
+	// workflow_run events fired by the workflow
+	head := headBranch(event)
+	for _, action := range []string{"requested", "in_progress", "completed"} {
+		payload := expr.ContextData{
+			"action": action,
+			"workflow_run": expr.ContextData{
+				"name":        f.WorkflowName(),
+				"path":        ".github/workflows/" + f.Path,
+				"event":       event.Name,
+				"head_branch": head,
+				"conclusion":  nil,
+			},
+			"workflow": expr.ContextData{"name": f.WorkflowName(), "path": ".github/workflows/" + f.Path},
+		}
+		if action == "completed" {
+			payload["workflow_run"].(expr.ContextData)["conclusion"] = "success"
+		}
+
+		children, truncated, err := r.route(&Event{Name: "workflow_run", Payload: payload}, "workflow_run", nil, ancestors, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, children...)
+		n.Truncated = n.Truncated || truncated
+	}
+
+	return n, nil
+}
+
+// localWorkflow returns the workflow referenced by a local `uses:` like
+// `./.github/workflows/build.yml`, or nil
+func (r *router) localWorkflow(uses string) *File {
+	if !strings.HasPrefix(uses, "./.github/workflows/") {
+		return nil
+	}
+
+	for _, f := range r.files {
+		if f.Path == path.Base(uses) {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// findWorkflows returns the workflows with the given file name, path, or name
+func (r *router) findWorkflows(ref string) []*File {
+	files := []*File{}
+	for _, f := range r.files {
+		if f.Path == path.Base(ref) || f.WorkflowName() == ref {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
+
+// headBranch returns the branch an event ran on
+func headBranch(event *Event) string {
+	if ref, ok := event.Payload["ref"].(string); ok {
+		return strings.TrimPrefix(ref, "refs/heads/")
+	}
+
+	if head, ok := lookupPath(event.Payload, "pull_request", "head", "ref").(string); ok {
+		return head
+	}
+
+	if head, ok := lookupPath(event.Payload, "workflow_run", "head_branch").(string); ok {
+		return head
+	}
+
+	return ""
+}
+
+// dispatch is an event sent by a step
+type dispatch struct {
+	event *Event
+
+	// workflow is the workflow started by a `workflow_dispatch` event
+	workflow string
+}
+
+// ghWorkflowRun matches a `gh workflow run` command up to the end of the shell command
+var ghWorkflowRun = regexp.MustCompile(`\bgh\s+workflow\s+run\b([^\n;&|]*)`)
+
+// shellWord matches a word of a shell command, in double or single quotes or unquoted
+var shellWord = regexp.MustCompile(`"([^"]*)"|'([^']*)'|(\S+)`)
+
+// ghWorkflowRunValueFlags are the flags of `gh workflow run` followed by a value
+var ghWorkflowRunValueFlags = map[string]bool{"-r": true, "--ref": true, "-f": true, "--raw-field": true, "-F": true, "--field": true}
+
+// ghWorkflowRunTarget returns the workflow started by `gh workflow run` with the arguments, or
+// false if the command doesn't name one or starts a workflow of another repository
+func ghWorkflowRunTarget(args string) (string, bool) {
+	words := shellWord.FindAllStringSubmatch(args, -1)
+
+	target := ""
+	for i := 0; i < len(words); i++ {
+		w, quoted := words[i][1]+words[i][2]+words[i][3], words[i][3] == ""
+
+		switch {
+		case quoted:
+		case w == "--repo" || strings.HasPrefix(w, "--repo=") || strings.HasPrefix(w, "-R"):
+			return "", false
+		case ghWorkflowRunValueFlags[w]:
+			i++
+			continue
+		case strings.HasPrefix(w, "-"):
+			continue
+		}
+
+		if target == "" {
+			target = w
+		}
+	}
+
+	return target, target != ""
+}
+
+// dispatches returns the events sent by a step. Known actions sending dispatches and
+// `gh workflow run` commands are recognized, values using expressions are not.
+func dispatches(step *actionlint.Step) []*dispatch {
+	ds := []*dispatch{}
+
+	switch e := step.Exec.(type) {
+	case *actionlint.ExecRun:
+		if e.Run == nil {
+			break
+		}
+
+		// Commands may continue on the next line
+		run := strings.ReplaceAll(e.Run.Value, "\\\n", " ")
+
+		for _, m := range ghWorkflowRun.FindAllStringSubmatch(run, -1) {
+			if target, ok := ghWorkflowRunTarget(m[1]); ok && !strings.Contains(target, "${{") {
+				ds = append(ds, &dispatch{event: &Event{Name: "workflow_dispatch", Payload: expr.ContextData{"inputs": expr.Unknown}}, workflow: target})
+			}
+		}
+
+	case *actionlint.ExecAction:
+		if e.Uses == nil {
+			break
+		}
+
+		action := strings.ToLower(strings.SplitN(e.Uses.Value, "@", 2)[0])
+		input := func(name string) string {
+			if in, ok := e.Inputs[name]; ok && in.Value != nil && !strings.Contains(in.Value.Value, "${{") {
+				return in.Value.Value
+			}
+			return ""
+		}
+
+		switch action {
+		case "peter-evans/repository-dispatch":
+			if t := input("event-type"); t != "" {
+				ds = append(ds, &dispatch{event: &Event{Name: "repository_dispatch", Payload: expr.ContextData{"action": t}}})
+			}
+
+		case "benc-uk/workflow-dispatch":
+			if w := input("workflow"); w != "" {
+				ds = append(ds, &dispatch{event: &Event{Name: "workflow_dispatch", Payload: expr.ContextData{"inputs": expr.Unknown}}, workflow: w})
+			}
+		}
+	}
+
+	return ds
+}
+
+// RouteString renders the cascade as an indented tree
+func RouteString(nodes []*RouteNode) string {
+	var b strings.Builder
+
+	var write func(n *RouteNode, indent string)
+	write = func(n *RouteNode, indent string) {
+		fmt.Fprintf(&b, "%s%s (%s", indent, n.File.Path, n.Via)
+		if n.Match.Status != StatusRun {
+			fmt.Fprintf(&b, ", %s", n.Match.Status)
+		}
+		b.WriteString(")")
+
+		switch {
+		case n.Cycle:
+			b.WriteString(" cycle")
+		case n.Truncated:
+			b.WriteString(" max depth reached")
+		}
+		b.WriteString("\n")
+
+		for _, c := range n.Children {
+			write(c, indent+"  ")
+		}
+	}
+
+	for _, n := range nodes {
+		write(n, "")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,302 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func loadTestWorkflows(t *testing.T, workflows map[string]string) []*File {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, src := range workflows {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir() error = %v", err)
+	}
+
+	return files
+}
+
+func TestLoadDir_Broken(t *testing.T) {
+	files := loadTestWorkflows(t, map[string]string{
+		"broken.yml": "on: [",
+		"ci.yml": `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+`,
+	})
+
+	if len(files) != 2 || files[0].Err == nil || files[0].Workflow != nil || files[1].Err != nil {
+		t.Fatalf("LoadDir() = %+v, want broken.yml with an error and ci.yml", files)
+	}
+
+	// Files that failed to load don't fail the routing
+	nodes, err := Route(files, &Event{Name: "push", Payload: expr.ContextData{}}, RouteOptions{})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if got := RouteString(nodes); got != "ci.yml (event)\n" {
+		t.Errorf("Route() = %q, want ci.yml", got)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	files := loadTestWorkflows(t, map[string]string{
+		"ci.yml": `
+name: CI
+on: push
+jobs:
+  build:
+    uses: ./.github/workflows/build.yml
+  notify:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: peter-evans/repository-dispatch@v2
+        with:
+          event-type: ci-done
+`,
+		"build.yml": `
+on: workflow_call
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make
+`,
+		"deploy.yml": `
+name: Deploy
+on:
+  workflow_run:
+    workflows: [CI]
+    types: [completed]
+    branches: [main]
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    steps:
+      - run: gh workflow run release.yml
+`,
+		"release.yml": `
+on: workflow_dispatch
+jobs:
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo release
+`,
+		"dispatched.yml": `
+on:
+  repository_dispatch:
+    types: [ci-done]
+jobs:
+  report:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo report
+`,
+		"docs.yml": `
+on: pull_request
+jobs:
+  docs:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo docs
+`,
+	})
+
+	nodes, err := Route(files, &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/main"}}, RouteOptions{})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"ci.yml (event)",
+		"  build.yml (workflow_call)",
+		"  dispatched.yml (repository_dispatch)",
+		"  deploy.yml (workflow_run)",
+		"    release.yml (workflow_dispatch)",
+		"",
+	}, "\n")
+	if got := RouteString(nodes); got != want {
+		t.Errorf("Route() =\n%v\nwant\n%v", got, want)
+	}
+
+	// Pushes to other branches don't trigger the deployment
+	nodes, err = Route(files, &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/dev"}}, RouteOptions{})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if got := RouteString(nodes); strings.Contains(got, "deploy.yml") {
+		t.Errorf("Route() =\n%v\nwant no deploy.yml", got)
+	}
+}
+
+func TestRoute_CycleAndDepth(t *testing.T) {
+	files := loadTestWorkflows(t, map[string]string{
+		"a.yml": `
+name: A
+on:
+  push:
+  workflow_run:
+    workflows: [B]
+    types: [completed]
+jobs:
+  a:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo a
+`,
+		"b.yml": `
+name: B
+on:
+  workflow_run:
+    workflows: [A]
+    types: [completed]
+jobs:
+  b:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo b
+`,
+	})
+
+	event := &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/main"}}
+
+	nodes, err := Route(files, event, RouteOptions{MaxDepth: 5})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"a.yml (event)",
+		"  b.yml (workflow_run)",
+		"    a.yml (workflow_run) cycle",
+		"",
+	}, "\n")
+	if got := RouteString(nodes); got != want {
+		t.Errorf("Route() =\n%v\nwant\n%v", got, want)
+	}
+
+	nodes, err = Route(files, event, RouteOptions{MaxDepth: 1})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	if got := RouteString(nodes); got != "a.yml (event) max depth reached\n" {
+		t.Errorf("Route() =\n%v", got)
+	}
+}
+
+func TestRoute_Options(t *testing.T) {
+	files := loadTestWorkflows(t, map[string]string{
+		"ci.yml": `
+on: push
+jobs:
+  build:
+    runs-on: ${{ vars.RUNNER }}
+    steps:
+      - if: runner.os == 'Windows'
+        run: gh workflow run release.yml
+`,
+		"release.yml": `
+on: workflow_dispatch
+jobs:
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo release
+`,
+	})
+
+	event := &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/main"}}
+
+	nodes, err := Route(files, event, RouteOptions{Options: Options{RunnerProfile: LookupRunnerProfile("ubuntu-latest")}})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+	if got := RouteString(nodes); got != "ci.yml (event)\n" {
+		t.Errorf("Route() =\n%v", got)
+	}
+}
+
+func TestRoute_MatrixDispatch(t *testing.T) {
+	files := loadTestWorkflows(t, map[string]string{
+		"ci.yml": `
+on: push
+jobs:
+  build:
+    strategy:
+      matrix:
+        os: [ubuntu-latest, windows-latest]
+    runs-on: ${{ matrix.os }}
+    steps:
+      - if: matrix.os == 'windows-latest'
+        run: gh workflow run release.yml
+`,
+		"release.yml": `
+on: workflow_dispatch
+jobs:
+  release:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo release
+`,
+	})
+
+	nodes, err := Route(files, &Event{Name: "push", Payload: expr.ContextData{"ref": "refs/heads/main"}}, RouteOptions{})
+	if err != nil {
+		t.Fatalf("Route() error = %v", err)
+	}
+
+	want := "ci.yml (event)\n  release.yml (workflow_dispatch)\n"
+	if got := RouteString(nodes); got != want {
+		t.Errorf("Route() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func Test_dispatches(t *testing.T) {
+	tests := []struct {
+		run  string
+		want []string
+	}{
+		{"gh workflow run release.yml", []string{"release.yml"}},
+		{"gh workflow run --ref main deploy.yml", []string{"deploy.yml"}},
+		{"gh workflow run -r main -f env=prod --json deploy.yml", []string{"deploy.yml"}},
+		{"gh workflow run --ref=main deploy.yml -F version=1", []string{"deploy.yml"}},
+		{`gh workflow run "Deploy app" --ref main`, []string{"Deploy app"}},
+		{"gh workflow run \\\n  --ref main \\\n  deploy.yml", []string{"deploy.yml"}},
+		{"gh workflow run a.yml && gh workflow run b.yml", []string{"a.yml", "b.yml"}},
+		{"gh workflow run --repo octo-org/other deploy.yml", []string{}},
+		{"gh workflow run -R octo-org/other deploy.yml", []string{}},
+		{"gh workflow run ${{ inputs.workflow }}", []string{}},
+		{"gh workflow run --ref main", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.run, func(t *testing.T) {
+			w := parseWorkflow(t, "on: push\njobs:\n  a:\n    runs-on: ubuntu-latest\n    steps:\n      - run: |\n          "+strings.ReplaceAll(tt.run, "\n", "\n          ")+"\n")
+
+			got := []string{}
+			for _, d := range dispatches(w.Jobs["a"].Steps[0]) {
+				got = append(got, d.workflow)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dispatches() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package workflow
+
+import (
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// RunnerProfile is the `runner` context of a GitHub-hosted runner image. DefaultEnv derives the
+// RUNNER_ environment variables of the image from the context.
+type RunnerProfile struct {
+	// Image is the versioned label of the image, like `ubuntu-24.04`
+	Image string
+
+	// Aliases are other `runs-on` labels selecting the image, like `ubuntu-latest`
+	Aliases []string
+
+	OS        string
+	Arch      string
+	Temp      string
+	ToolCache string
+}
+
+// RunnerProfiles are the profiles of the GitHub-hosted runner images. `-latest` labels select the
+// images they pointed to in October 2026.
+var RunnerProfiles = []*RunnerProfile{
+	{Image: "ubuntu-22.04", OS: "Linux", Arch: "X64", Temp: "/home/runner/work/_temp", ToolCache: "/opt/hostedtoolcache"},
+	{Image: "ubuntu-24.04", Aliases: []string{"ubuntu-latest"}, OS: "Linux", Arch: "X64", Temp: "/home/runner/work/_temp", ToolCache: "/opt/hostedtoolcache"},
+	{Image: "ubuntu-22.04-arm", OS: "Linux", Arch: "ARM64", Temp: "/home/runner/work/_temp", ToolCache: "/opt/hostedtoolcache"},
+	{Image: "ubuntu-24.04-arm", OS: "Linux", Arch: "ARM64", Temp: "/home/runner/work/_temp", ToolCache: "/opt/hostedtoolcache"},
+	{Image: "windows-2022", OS: "Windows", Arch: "X64", Temp: `D:\a\_temp`, ToolCache: `C:\hostedtoolcache\windows`},
+	{Image: "windows-2025", Aliases: []string{"windows-latest"}, OS: "Windows", Arch: "X64", Temp: `D:\a\_temp`, ToolCache: `C:\hostedtoolcache\windows`},
+	{Image: "windows-11-arm", OS: "Windows", Arch: "ARM64", Temp: `C:\a\_temp`, ToolCache: `C:\hostedtoolcache\windows`},
+	{Image: "macos-13", OS: "macOS", Arch: "X64", Temp: "/Users/runner/work/_temp", ToolCache: "/Users/runner/hostedtoolcache"},
+	{Image: "macos-14", OS: "macOS", Arch: "ARM64", Temp: "/Users/runner/work/_temp", ToolCache: "/Users/runner/hostedtoolcache"},
+	{Image: "macos-15", Aliases: []string{"macos-latest"}, OS: "macOS", Arch: "ARM64", Temp: "/Users/runner/work/_temp", ToolCache: "/Users/runner/hostedtoolcache"},
+}
+
+// LookupRunnerProfile returns the profile of the image selected by a `runs-on` label, or nil if
+// the label doesn't select a GitHub-hosted image
+func LookupRunnerProfile(label string) *RunnerProfile {
+	for _, p := range RunnerProfiles {
+		if strings.EqualFold(p.Image, label) || containsFold(p.Aliases, label) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// Context returns the `runner` context of the image. The workspace is named after the repository,
+// so it's unknown.
+func (p *RunnerProfile) Context() expr.ContextData {
+	return expr.ContextData{
+		"name":        "GitHub Actions",
+		"os":          p.OS,
+		"arch":        p.Arch,
+		"temp":        p.Temp,
+		"tool_cache":  p.ToolCache,
+		"environment": "github-hosted",
+		"workspace":   expr.Unknown,
+	}
+}
+
+// runnerProfile returns the profile of the image selected by the `runs-on` labels of the job. The
+// first label selecting a GitHub-hosted image is used. Known is false if a label depends on values
+// only known at runtime.
+func runnerProfile(job *actionlint.Job, ctx expr.ContextData) (p *RunnerProfile, known bool) {
+	if job.RunsOn == nil {
+		return nil, true
+	}
+
+	for _, l := range job.RunsOn.Labels {
+		v, err := evaluateString(l.Value, ctx, "jobs.<job_id>.runs-on")
+		if err != nil || v == expr.Unknown {
+			return nil, false
+		}
+
+		labels, ok := v.([]interface{})
+		if !ok {
+			labels = []interface{}{v}
+		}
+
+		for _, label := range labels {
+			if label, ok := label.(string); ok {
+				if p := LookupRunnerProfile(label); p != nil {
+					return p, true
+				}
+			}
+		}
+	}
+
+	return nil, true
+}
+
+// setRunner sets the `runner` context of a run of the job, unless the context is given in the
+// options
+func (s *simulator) setRunner(job *actionlint.Job, ctx expr.ContextData) {
+	if _, ok := s.opts.Contexts["runner"]; ok {
+		return
+	}
+
+	p, known := s.opts.RunnerProfile, true
+	if p == nil {
+		p, known = runnerProfile(job, ctx)
+	}
+
+	if known && p != nil {
+		ctx["runner"] = p.Context()
+	} else {
+		ctx["runner"] = expr.Unknown
+	}
+}
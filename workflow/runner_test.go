@@ -0,0 +1,107 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestLookupRunnerProfile(t *testing.T) {
+	tests := []struct {
+		label     string
+		wantImage string
+	}{
+		{"ubuntu-latest", "ubuntu-24.04"},
+		{"ubuntu-22.04", "ubuntu-22.04"},
+		{"Windows-Latest", "windows-2025"},
+		{"macos-14", "macos-14"},
+		{"self-hosted", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			p := LookupRunnerProfile(tt.label)
+
+			got := ""
+			if p != nil {
+				got = p.Image
+			}
+			if got != tt.wantImage {
+				t.Errorf("LookupRunnerProfile() = %v, want %v", got, tt.wantImage)
+			}
+		})
+	}
+}
+
+func TestSimulate_Runner(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ${{ matrix.os }}
+    strategy:
+      matrix:
+        os: [ubuntu-latest, windows-latest, macos-14, self-hosted]
+    steps:
+      - name: Windows
+        if: runner.os == 'Windows'
+        run: choco install make
+      - name: ARM
+        if: runner.arch == 'ARM64'
+        run: echo arm
+`)
+
+	stepStatuses := func(plan *Plan) [][]Status {
+		r := [][]Status{}
+		for _, run := range plan.Job("build").Runs {
+			s := []Status{}
+			for _, st := range run.Steps {
+				s = append(s, st.Status)
+			}
+			r = append(r, s)
+		}
+		return r
+	}
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := [][]Status{
+		{StatusSkipped, StatusSkipped},
+		{StatusRun, StatusSkipped},
+		{StatusSkipped, StatusRun},
+		{StatusUndecidable, StatusUndecidable},
+	}
+	if got := stepStatuses(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("Simulate() steps = %v, want %v", got, want)
+	}
+
+	// Every run uses the given profile
+	plan, err = Simulate(w, &Event{Name: "push"}, Options{RunnerProfile: LookupRunnerProfile("windows-11-arm")})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	for _, s := range stepStatuses(plan) {
+		if !reflect.DeepEqual(s, []Status{StatusRun, StatusRun}) {
+			t.Errorf("Simulate() steps = %v, want all run", s)
+		}
+	}
+
+	// A given runner context takes precedence
+	plan, err = Simulate(w, &Event{Name: "push"}, Options{
+		Contexts:      expr.ContextData{"runner": expr.ContextData{"os": "Linux", "arch": "X64"}},
+		RunnerProfile: LookupRunnerProfile("windows-2022"),
+	})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	for _, s := range stepStatuses(plan) {
+		if !reflect.DeepEqual(s, []Status{StatusSkipped, StatusSkipped}) {
+			t.Errorf("Simulate() steps = %v, want all skipped", s)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+	"github.com/robfig/cron"
+)
+
+// MaxScheduledRuns is the maximum number of runs SimulateSchedule returns, to guard against
+// windows which are too large for the schedules
+const MaxScheduledRuns = 1000
+
+// cronParser parses the five field cron syntax used by `on.schedule`
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// ScheduledRun is a run of a workflow started by one of its schedules
+type ScheduledRun struct {
+	// Time is the UTC instant the schedule fires
+	Time time.Time
+
+	// Cron is the cron expression of the schedule, available as `github.event.schedule`
+	Cron string
+
+	Plan *Plan
+}
+
+// ScheduleTimes returns the UTC instants the cron expression fires in the window from `from`
+// (inclusive) to `to` (exclusive)
+func ScheduleTimes(spec string, from time.Time, to time.Time) ([]time.Time, error) {
+	s, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %v", spec, err)
+	}
+
+	times := []time.Time{}
+
+	// Next returns instants strictly after the given time, start just before the window
+	for t := s.Next(from.UTC().Add(-time.Nanosecond)); !t.IsZero() && t.Before(to); t = s.Next(t) {
+		if len(times) == MaxScheduledRuns {
+			return nil, fmt.Errorf("cron expression %q fires more than %d times in the window", spec, MaxScheduledRuns)
+		}
+
+		times = append(times, t)
+	}
+
+	return times, nil
+}
+
+// SimulateSchedule simulates the runs started by the `on.schedule` entries of the workflow in the
+// window from `from` (inclusive) to `to` (exclusive). Runs are ordered by time, and by the order
+// of the schedules for schedules firing at the same time. Each run is simulated for a `schedule`
+// event with the cron expression as `github.event.schedule`.
+func SimulateSchedule(w *actionlint.Workflow, from time.Time, to time.Time, opts Options) ([]*ScheduledRun, error) {
+	var schedule *actionlint.ScheduledEvent
+	for _, on := range w.On {
+		if s, ok := on.(*actionlint.ScheduledEvent); ok {
+			schedule = s
+			break
+		}
+	}
+
+	if schedule == nil {
+		return nil, errors.New("workflow is not triggered by schedule events")
+	}
+
+	runs := []*ScheduledRun{}
+	for _, c := range schedule.Cron {
+		times, err := ScheduleTimes(c.Value, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range times {
+			runs = append(runs, &ScheduledRun{Time: t, Cron: c.Value})
+		}
+	}
+
+	if len(runs) > MaxScheduledRuns {
+		return nil, fmt.Errorf("schedules fire more than %d times in the window", MaxScheduledRuns)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Time.Before(runs[j].Time) })
+
+	for _, r := range runs {
+		event := &Event{Name: "schedule", Payload: expr.ContextData{"schedule": r.Cron}}
+
+		ropts := opts
+		ropts.Contexts = scheduleContexts(opts.Contexts, event)
+
+		plan, err := Simulate(w, event, ropts)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Plan = plan
+	}
+
+	return runs, nil
+}
+
+// scheduleContexts returns the contexts with a `github` context describing the schedule event,
+// see GitHubContext. Scheduled runs run on the latest commit of the default branch, which is the
+// branch of the `ref` of a given `github` context. Other properties of a given `github` context
+// are kept.
+func scheduleContexts(contexts expr.ContextData, event *Event) expr.ContextData {
+	ctx := copyContext(contexts)
+
+	given, _ := contexts["github"].(expr.ContextData)
+
+	repo := Repository{}
+	if name, ok := given["repository"].(string); ok {
+		repo.FullName = name
+	}
+	if ref, ok := given["ref"].(string); ok && strings.HasPrefix(ref, "refs/heads/") {
+		repo.DefaultBranch = strings.TrimPrefix(ref, "refs/heads/")
+	}
+
+	github := GitHubContext(event, repo, Run{})
+	for k, v := range given {
+		if k != "event_name" && k != "event" {
+			github[k] = v
+		}
+	}
+
+	ctx["github"] = github
+
+	return ctx
+}
@@ -0,0 +1,180 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestScheduleTimes(t *testing.T) {
+	from := time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		spec    string
+		to      time.Time
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "start of the window is included",
+			spec: "0 0 * * *",
+			to:   from.AddDate(0, 0, 3),
+			want: []string{"2022-03-04T00:00:00Z", "2022-03-05T00:00:00Z", "2022-03-06T00:00:00Z"},
+		},
+		{
+			name: "weekly",
+			spec: "30 5 * * 1",
+			to:   from.AddDate(0, 0, 14),
+			want: []string{"2022-03-07T05:30:00Z", "2022-03-14T05:30:00Z"},
+		},
+		{
+			name: "steps",
+			spec: "*/15 1 4 3 *",
+			to:   from.AddDate(0, 0, 1),
+			want: []string{"2022-03-04T01:00:00Z", "2022-03-04T01:15:00Z", "2022-03-04T01:30:00Z", "2022-03-04T01:45:00Z"},
+		},
+		{
+			name: "never fires",
+			spec: "0 0 30 2 *",
+			to:   from.AddDate(1, 0, 0),
+			want: []string{},
+		},
+		{
+			name:    "invalid",
+			spec:    "0 0 * *",
+			to:      from.AddDate(0, 0, 1),
+			wantErr: true,
+		},
+		{
+			name:    "too many runs",
+			spec:    "* * * * *",
+			to:      from.AddDate(0, 0, 1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			times, err := ScheduleTimes(tt.spec, from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ScheduleTimes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got := []string{}
+			for _, t := range times {
+				got = append(got, t.Format(time.RFC3339))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ScheduleTimes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulateSchedule(t *testing.T) {
+	w := parseWorkflow(t, `
+on:
+  schedule:
+    - cron: '0 3 * * *'
+    - cron: '0 3 * * 0'
+jobs:
+  nightly:
+    if: github.event.schedule == '0 3 * * *'
+    runs-on: ubuntu-latest
+    steps:
+      - run: make nightly
+  weekly:
+    if: github.event.schedule == '0 3 * * 0' && github.ref == 'refs/heads/main'
+    runs-on: ubuntu-latest
+    steps:
+      - run: make weekly
+`)
+
+	from := time.Date(2022, 3, 5, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	runs, err := SimulateSchedule(w, from, from.AddDate(0, 0, 2), Options{
+		Contexts: expr.ContextData{"github": expr.ContextData{"ref": "refs/heads/main", "event_name": "push"}},
+	})
+	if err != nil {
+		t.Fatalf("SimulateSchedule() error = %v", err)
+	}
+
+	type run struct {
+		time    string
+		cron    string
+		nightly Status
+		weekly  Status
+	}
+
+	got := []run{}
+	for _, r := range runs {
+		got = append(got, run{r.Time.Format(time.RFC3339), r.Cron, r.Plan.Job("nightly").Status, r.Plan.Job("weekly").Status})
+	}
+
+	want := []run{
+		{"2022-03-06T03:00:00Z", "0 3 * * *", StatusRun, StatusSkipped},
+		{"2022-03-06T03:00:00Z", "0 3 * * 0", StatusSkipped, StatusRun},
+		{"2022-03-07T03:00:00Z", "0 3 * * *", StatusRun, StatusSkipped},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SimulateSchedule() = %v, want %v", got, want)
+	}
+
+	// Other options are kept
+	runs, err = SimulateSchedule(parseWorkflow(t, `
+on:
+  schedule:
+    - cron: '0 3 * * *'
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - if: runner.os == 'Windows'
+        run: echo windows
+`), from, from.AddDate(0, 0, 1), Options{RunnerProfile: LookupRunnerProfile("windows-latest")})
+	if err != nil {
+		t.Fatalf("SimulateSchedule() error = %v", err)
+	}
+	if s := runs[0].Plan.Job("build").Runs[0].Steps[0].Status; s != StatusRun {
+		t.Errorf("SimulateSchedule() step with runner profile = %v, want %v", s, StatusRun)
+	}
+
+	if _, err := SimulateSchedule(parseWorkflow(t, "on: push\njobs:\n  a:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo\n"), from, from, Options{}); err == nil {
+		t.Errorf("SimulateSchedule() error = nil, want error for workflow without schedule")
+	}
+}
+
+func Test_scheduleContexts(t *testing.T) {
+	event := &Event{Name: "schedule", Payload: expr.ContextData{"schedule": "0 3 * * *"}}
+
+	ctx := scheduleContexts(expr.ContextData{
+		"github": expr.ContextData{"repository": "octo/repo", "ref": "refs/heads/main", "event_name": "push"},
+	}, event)
+	github := ctx["github"].(expr.ContextData)
+
+	want := expr.ContextData{
+		"event_name": "schedule",
+		"repository": "octo/repo",
+		"ref":        "refs/heads/main",
+		"ref_name":   "main",
+		"ref_type":   "branch",
+		"sha":        expr.Unknown,
+	}
+	for k, v := range want {
+		if github[k] != v {
+			t.Errorf("scheduleContexts() github.%s = %v, want %v", k, github[k], v)
+		}
+	}
+
+	// Without a default branch the ref is unknown
+	github = scheduleContexts(expr.ContextData{}, event)["github"].(expr.ContextData)
+	for _, k := range []string{"ref", "ref_name", "sha"} {
+		if github[k] != expr.Unknown {
+			t.Errorf("scheduleContexts() github.%s = %v, want unknown", k, github[k])
+		}
+	}
+}
@@ -0,0 +1,560 @@
+// Package workflow simulates workflow runs on top of the expression interpreter. It decides which
+// jobs and steps of a workflow parsed by actionlint would run for an event, without running them.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// Event is the event triggering the workflow
+type Event struct {
+	// Name is the name of the event, like `push` or `pull_request`
+	Name string
+
+	// Payload is the webhook payload of the event, available as `github.event`
+	Payload expr.ContextData
+
+	// ChangedFiles are the files changed by a push or pull request, used to match path filters.
+	// Nil if they are unknown.
+	ChangedFiles []string
+}
+
+// Options configures optional behavior of Simulate
+type Options struct {
+	// Contexts are added to the contexts available to expressions, e.g. `vars` or `secrets`.
+	// Properties of a `github` context are merged with the ones derived from the event. Contexts
+	// that are not given are unknown, except for `vars` and `inputs` which default to empty
+	// objects.
+	Contexts expr.ContextData
+
+	// RunnerProfile is the image all jobs run on, e.g. to check conditions on `runner.os` for
+	// every image. By default the image is selected by the `runs-on` labels of each job, and the
+	// `runner` context is unknown for self-hosted runners. A `runner` context given in Contexts
+	// takes precedence.
+	RunnerProfile *RunnerProfile
+
+	// Dir is the root of the repository. Jobs calling local reusable workflows like
+	// `./.github/workflows/deploy.yml` simulate the called workflow loaded from Dir, see
+	// JobRunPlan.Workflow, and steps running local composite actions like `./.github/actions/setup`
+	// simulate the steps of the action, see StepPlan.Steps. Without Dir, neither is simulated.
+	Dir string
+
+	// Catalog provides the metadata of remote actions like `actions/checkout@v4`, see
+	// StepPlan.Inputs and StepPlan.Warnings. Steps running remote actions without metadata have
+	// unknown outputs.
+	Catalog *Catalog
+}
+
+// Simulate decides which jobs and steps of the workflow would run for the given event. Job and
+// step conditions, `needs` dependencies, and matrices are evaluated. Jobs and steps that run are
+// assumed to succeed, values only known at runtime like step outputs are unknown.
+func Simulate(w *actionlint.Workflow, event *Event, opts Options) (*Plan, error) {
+	if event == nil {
+		return nil, errors.New("event is required")
+	}
+
+	s := &simulator{
+		workflow: w,
+		event:    event,
+		opts:     opts,
+		results:  map[string]*JobResult{},
+		inputs:   expr.ContextData{},
+	}
+
+	if err := s.dispatchInputs(); err != nil {
+		return nil, err
+	}
+
+	return s.simulate()
+}
+
+// simulate simulates the jobs of the workflow in the order they would run
+func (s *simulator) simulate() (*Plan, error) {
+	order, err := jobOrder(s.workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	for _, job := range order {
+		jp := s.simulateJob(job)
+		jp.Result = jobResult(jp)
+		s.results[strings.ToLower(jp.ID)] = jp.Result
+		plan.Jobs = append(plan.Jobs, jp)
+	}
+
+	return plan, nil
+}
+
+// jobOrder returns the jobs of the workflow ordered by their dependencies. Jobs that can run at
+// the same time are ordered by ID.
+func jobOrder(w *actionlint.Workflow) ([]*actionlint.Job, error) {
+	ids := make([]string, 0, len(w.Jobs))
+	for id := range w.Jobs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Job IDs are stored in lower case
+	deps := map[string][]string{}
+	for _, id := range ids {
+		for _, n := range w.Jobs[id].Needs {
+			need := strings.ToLower(n.Value)
+			if _, ok := w.Jobs[need]; !ok {
+				return nil, fmt.Errorf("job %q needs unknown job %q", id, n.Value)
+			}
+			deps[id] = append(deps[id], need)
+		}
+	}
+
+	order := []*actionlint.Job{}
+	done := map[string]bool{}
+
+	for len(order) < len(ids) {
+		// Jobs whose dependencies are all done
+		ready := []string{}
+
+		for _, id := range ids {
+			if done[id] {
+				continue
+			}
+
+			r := true
+			for _, d := range deps[id] {
+				if !done[d] {
+					r = false
+					break
+				}
+			}
+
+			if r {
+				ready = append(ready, id)
+			}
+		}
+
+		for _, id := range ready {
+			done[id] = true
+			order = append(order, w.Jobs[id])
+		}
+
+		if len(ready) == 0 {
+			cycle := []string{}
+			for _, id := range ids {
+				if !done[id] {
+					cycle = append(cycle, id)
+				}
+			}
+
+			return nil, errors.New("dependency cycle between jobs " + strings.Join(cycle, ", "))
+		}
+	}
+
+	return order, nil
+}
+
+// simulator holds the state of a single simulation
+type simulator struct {
+	workflow *actionlint.Workflow
+	event    *Event
+	opts     Options
+
+	// results are the results of the jobs simulated so far, by ID in lower case
+	results map[string]*JobResult
+
+	// inputs is the `inputs` context, unknown if the inputs of a dispatch are unknown
+	inputs interface{}
+
+	// depth is the number of workflows calling the simulated workflow
+	depth int
+}
+
+// dispatchInputs types and validates the inputs of a `workflow_dispatch` event for workflows
+// declaring them. The payload seen as `github.event` gets the legacy string form of the inputs.
+func (s *simulator) dispatchInputs() error {
+	if s.event.Name != "workflow_dispatch" {
+		return nil
+	}
+
+	supplied, ok := s.event.Payload["inputs"].(expr.ContextData)
+	if !ok && s.event.Payload["inputs"] != nil {
+		s.inputs = expr.Unknown
+		return nil
+	}
+
+	// Inputs of workflows without a workflow_dispatch trigger can't be validated
+	if dispatchEvent(s.workflow) == nil {
+		if ok {
+			s.inputs = supplied
+		}
+		return nil
+	}
+
+	values := map[string]string{}
+	for name, v := range supplied {
+		r := expr.NewEvaluationResult(v)
+		if !r.Primitive() {
+			return fmt.Errorf("input %q must be a primitive value", name)
+		}
+		values[name] = r.CoerceString()
+	}
+
+	inputs, eventInputs, err := DispatchInputs(s.workflow, values)
+	if err != nil {
+		return err
+	}
+
+	payload := copyContext(s.event.Payload)
+	payload["inputs"] = eventInputs
+	s.event = &Event{Name: s.event.Name, Payload: payload, ChangedFiles: s.event.ChangedFiles}
+	s.inputs = inputs
+
+	return nil
+}
+
+func (s *simulator) simulateJob(job *actionlint.Job) *JobPlan {
+	jp := &JobPlan{
+		ID:   job.ID.Value,
+		Name: job.ID.Value,
+	}
+	if job.Name != nil {
+		jp.Name = job.Name.Value
+	}
+
+	status, reason := NeedsStatus(s.workflow, job, s.results)
+	ctx := s.jobContext(job)
+
+	d := evaluateCondition(job.If, ctx, "jobs.<job_id>.if", status, reason)
+	jp.Status, jp.Reason = d.status, d.reason
+
+	if jp.Status == StatusSkipped || jp.Status == StatusError {
+		return jp
+	}
+
+	matrix, known, err := ExpandMatrix(job, ctx)
+	if err != nil {
+		jp.Status, jp.Reason = StatusError, err.Error()
+		return jp
+	}
+
+	if !known {
+		if jp.Status == StatusRun {
+			jp.Status, jp.Reason = StatusUndecidable, "matrix depends on values only known at runtime"
+		}
+
+		ctx["matrix"] = expr.Unknown
+		ctx["strategy"] = expr.Unknown
+		s.setRunContexts(job, ctx)
+		jp.Runs = []*JobRunPlan{s.simulateRun(job, jp.Name, nil, ctx)}
+
+		return jp
+	}
+
+	if matrix == nil {
+		ctx["matrix"] = expr.ContextData{}
+		ctx["strategy"] = strategyContext(job, 0, 1)
+		s.setRunContexts(job, ctx)
+
+		// Names of jobs without a matrix can be evaluated once
+		if name, err := JobDisplayName(job, nil, nil, ctx); err == nil {
+			jp.Name = name
+		}
+
+		jp.Runs = []*JobRunPlan{s.simulateRun(job, jp.Name, nil, ctx)}
+
+		return jp
+	}
+
+	if len(matrix.Combinations) == 0 {
+		jp.Status, jp.Reason = StatusSkipped, "all matrix combinations are excluded"
+		return jp
+	}
+
+	for idx, m := range matrix.Combinations {
+		rctx := copyContext(ctx)
+		rctx["matrix"] = m
+		rctx["strategy"] = strategyContext(job, idx, len(matrix.Combinations))
+		s.setRunContexts(job, rctx)
+
+		name, err := JobDisplayName(job, m, matrix, rctx)
+		if err != nil {
+			name = jp.Name
+		}
+
+		jp.Runs = append(jp.Runs, s.simulateRun(job, name, m, rctx))
+	}
+
+	return jp
+}
+
+// jobContext returns the contexts available to the job
+func (s *simulator) jobContext(job *actionlint.Job) expr.ContextData {
+	ctx := expr.ContextData{
+		"vars":    expr.ContextData{},
+		"inputs":  s.inputs,
+		"secrets": expr.Unknown,
+		"runner":  expr.Unknown,
+		"job":     expr.Unknown,
+	}
+
+	for k, v := range s.opts.Contexts {
+		ctx[k] = v
+	}
+
+	github := expr.ContextData{}
+	if g, ok := s.opts.Contexts["github"].(expr.ContextData); ok {
+		github = copyContext(g)
+	}
+	if _, ok := github["event_name"]; !ok {
+		github["event_name"] = s.event.Name
+	}
+	if _, ok := github["event"]; !ok {
+		payload := s.event.Payload
+		if payload == nil {
+			payload = expr.ContextData{}
+		}
+		github["event"] = payload
+	}
+	ctx["github"] = github
+
+	ctx["needs"] = NeedsContext(job, s.results)
+
+	return ctx
+}
+
+// simulateRun simulates a single run of the job
+func (s *simulator) simulateRun(job *actionlint.Job, name string, matrix expr.ContextData, ctx expr.ContextData) *JobRunPlan {
+	if job.WorkflowCall != nil && job.WorkflowCall.Uses != nil && s.opts.Dir != "" {
+		if path, ok := localPath(job.WorkflowCall.Uses.Value); ok {
+			return s.simulateCall(job, name, matrix, ctx, path)
+		}
+	}
+
+	steps, status, final := s.simulateSteps(job, ctx)
+
+	run := &JobRunPlan{Name: name, Matrix: matrix, Steps: steps, Result: JobSuccess}
+	switch status {
+	case expr.StatusFailure:
+		run.Result = JobFailure
+	case expr.StatusCancelled:
+		run.Result = JobCancelled
+	case expr.StatusUnknown:
+		run.Result = expr.Unknown
+	}
+
+	outputs, err := JobOutputs(job, final)
+	if err != nil {
+		run.Result, run.Reason, run.Outputs = JobFailure, err.Error(), expr.Unknown
+	} else {
+		run.Outputs = outputs
+	}
+
+	return run
+}
+
+// simulateSteps decides which steps of a single run of the job would run. It returns the status of
+// the job after the last step, and the contexts after the last step.
+func (s *simulator) simulateSteps(job *actionlint.Job, jobCtx expr.ContextData) ([]*StepPlan, expr.Status, expr.ContextData) {
+	plans, steps, _ := s.runSteps(job.Steps, jobStepsKey, jobCtx, expr.StatusSuccess, "", 0)
+
+	status, _ := steps.Status()
+
+	final := copyContext(jobCtx)
+	final["steps"] = steps.Context()
+
+	return plans, status, final
+}
+
+// runSteps decides which of the steps of a job or of a composite action would run. Key is the
+// workflow key of the steps, see jobStepsKey, and scope the contexts of the job or the action.
+// Status and reason are the status before the first step, depth is the number of composite
+// actions running the steps. It returns the variables the steps write to GITHUB_ENV.
+func (s *simulator) runSteps(steps []*actionlint.Step, key string, scope expr.ContextData, parent expr.Status, parentReason string, depth int) ([]*StepPlan, *StepsContext, interface{}) {
+	plans := []*StepPlan{}
+	sc := &StepsContext{}
+	ids := StepIDs(steps)
+
+	// githubEnv are the variables written to GITHUB_ENV by earlier steps
+	var githubEnv interface{} = expr.ContextData{}
+
+	// actions are the actions run by earlier steps by step ID, nil if unknown
+	actions := map[string]*Action{}
+
+	for idx, step := range steps {
+		sp := &StepPlan{
+			Index: idx,
+			ID:    ids[idx],
+			Name:  stepName(step),
+		}
+
+		ctx := copyContext(scope)
+		ctx["steps"] = sc.Context()
+
+		env, err := stepEnv(step, ctx, githubEnv, key)
+		if err != nil {
+			env = expr.Unknown
+		}
+		ctx["env"] = env
+		sp.Env = env
+
+		// Steps that run are assumed to succeed, the job only fails if a condition can't be
+		// evaluated or a composite action fails
+		status, reason := sc.Status()
+		if parent == expr.StatusFailure || status == expr.StatusSuccess {
+			status, reason = parent, parentReason
+		}
+		d := evaluateCondition(step.If, ctx, key+".if", status, reason)
+		sp.Status, sp.Reason = d.status, d.reason
+
+		writes := githubEnvWrites(step, ctx, key)
+		sp.Warnings = CheckOutputReferences(step, actions)
+
+		// Actions of steps that don't run are not loaded
+		var a *Action
+		var c *compositeRun
+		if sp.Status == StatusRun || sp.Status == StatusUndecidable {
+			if a, err = s.action(step); err != nil {
+				sp.Status, sp.Reason = StatusError, err.Error()
+			}
+		}
+
+		if a != nil {
+			sp.Warnings = append(sp.Warnings, CheckWith(a, step)...)
+
+			inputs, err := s.actionInputs(a, step, ctx, key)
+			if err != nil {
+				sp.Status, sp.Reason = StatusError, err.Error()
+			}
+			sp.Inputs = inputs
+
+			// The runner passes the inputs of JavaScript and Docker actions as variables
+			if err == nil && a.Runs.Using != "composite" {
+				sp.Env = mergeEnv(sp.Env, InputEnv(inputs))
+			}
+
+			if err == nil && a.Runs.Using == "composite" {
+				if c, err = s.simulateComposite(a, inputs, ctx, status, reason, depth); err != nil {
+					sp.Status, sp.Reason = StatusError, err.Error()
+				} else {
+					sp.Steps, writes = c.steps, c.githubEnv
+				}
+			}
+		}
+		actions[strings.ToLower(sp.ID)] = a
+
+		var outcome, outputs interface{} = expr.Unknown, expr.Unknown
+		switch sp.Status {
+		case StatusRun:
+			outcome = StepSuccess
+		case StatusSkipped:
+			outcome = StepSkipped
+		case StatusError:
+			outcome = StepFailure
+		}
+
+		if c != nil && sp.Status == StatusRun {
+			outputs = c.outputs
+
+			switch c.status {
+			case expr.StatusFailure:
+				outcome, sp.Reason = StepFailure, c.reason
+			case expr.StatusCancelled:
+				outcome = StepCancelled
+			case expr.StatusUnknown:
+				outcome = expr.Unknown
+			}
+		}
+
+		sc.Complete(sp.ID, outcome, continueOnError(step, ctx, key), outputs)
+
+		switch sp.Status {
+		case StatusRun:
+			githubEnv = mergeEnv(githubEnv, writes)
+		case StatusUndecidable:
+			// Variables may or may not be written
+			if w, ok := writes.(expr.ContextData); !ok || len(w) > 0 {
+				githubEnv = expr.Unknown
+			}
+		}
+
+		plans = append(plans, sp)
+	}
+
+	return plans, sc, githubEnv
+}
+
+// stepName returns the name of the step, or the name GitHub displays for steps without one
+func stepName(step *actionlint.Step) string {
+	if step.Name != nil {
+		return step.Name.Value
+	}
+
+	switch e := step.Exec.(type) {
+	case *actionlint.ExecRun:
+		if e.Run != nil {
+			return "Run " + strings.SplitN(strings.TrimSpace(e.Run.Value), "\n", 2)[0]
+		}
+
+	case *actionlint.ExecAction:
+		if e.Uses != nil {
+			return "Run " + e.Uses.Value
+		}
+	}
+
+	return fmt.Sprintf("Step %d", step.Pos.Line)
+}
+
+// setRunContexts sets the contexts that differ between runs of a matrix job and aren't available
+// before the matrix is expanded: `runner` and the job's `env`
+func (s *simulator) setRunContexts(job *actionlint.Job, ctx expr.ContextData) {
+	s.setRunner(job, ctx)
+
+	env, err := JobEnv(s.workflow, job, ctx)
+	if err != nil {
+		env = expr.Unknown
+	}
+	ctx["env"] = env
+}
+
+// strategyContext returns the `strategy` context for the run with the given index
+func strategyContext(job *actionlint.Job, idx int, total int) expr.ContextData {
+	failFast, maxParallel := interface{}(true), interface{}(float64(total))
+
+	if st := job.Strategy; st != nil {
+		if st.FailFast != nil {
+			failFast = st.FailFast.Value
+			if st.FailFast.Expression != nil {
+				failFast = expr.Unknown
+			}
+		}
+
+		if st.MaxParallel != nil {
+			maxParallel = float64(st.MaxParallel.Value)
+			if st.MaxParallel.Expression != nil {
+				maxParallel = expr.Unknown
+			}
+		}
+	}
+
+	return expr.ContextData{
+		"fail-fast":    failFast,
+		"job-index":    float64(idx),
+		"job-total":    float64(total),
+		"max-parallel": maxParallel,
+	}
+}
+
+// copyContext returns a shallow copy of the given context data
+func copyContext(c expr.ContextData) expr.ContextData {
+	r := make(expr.ContextData, len(c))
+	for k, v := range c {
+		r[k] = v
+	}
+
+	return r
+}
@@ -0,0 +1,259 @@
+package workflow
+
+import (
+	"strings"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+func parseWorkflow(t *testing.T, src string) *actionlint.Workflow {
+	t.Helper()
+
+	w, err := ParseWorkflow([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseWorkflow() error = %v", err)
+	}
+
+	return w
+}
+
+func TestSimulate(t *testing.T) {
+	w := parseWorkflow(t, `
+on: [push, pull_request]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    strategy:
+      matrix:
+        os: [ubuntu, windows]
+    steps:
+      - uses: actions/checkout@v3
+      - id: test
+        run: |
+          make test
+          make lint
+      - name: Windows only
+        if: matrix.os == 'windows'
+        run: echo win
+      - name: Report
+        if: steps.test.outputs.failed == 'true'
+        run: echo report
+  deploy:
+    needs: build
+    if: github.event_name == 'push' && github.ref == 'refs/heads/main'
+    runs-on: ubuntu-latest
+    steps:
+      - run: ./deploy.sh
+  notify:
+    needs: deploy
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo notify
+  cleanup:
+    needs: deploy
+    if: always()
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo cleanup
+`)
+
+	plan, err := Simulate(w, &Event{Name: "pull_request", Payload: expr.ContextData{}}, Options{
+		Contexts: expr.ContextData{
+			"github": expr.ContextData{"ref": "refs/pull/1/merge"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"build: run",
+		"  build (ubuntu) {os: ubuntu}",
+		"    [0] Run actions/checkout@v3: run",
+		"    [1] Run make test: run",
+		"    [2] Windows only: skipped (condition `matrix.os == 'windows'` is false)",
+		"    [3] Report: undecidable (condition `steps.test.outputs.failed == 'true'` depends on values only known at runtime)",
+		"  build (windows) {os: windows}",
+		"    [0] Run actions/checkout@v3: run",
+		"    [1] Run make test: run",
+		"    [2] Windows only: run",
+		"    [3] Report: undecidable (condition `steps.test.outputs.failed == 'true'` depends on values only known at runtime)",
+		"deploy: skipped (condition `github.event_name == 'push' && github.ref == 'refs/heads/main'` is false)",
+		"cleanup: run",
+		"  [0] Run echo cleanup: run",
+		"notify: skipped (needed job \"deploy\" is skipped)",
+		"",
+	}, "\n")
+
+	if plan.String() != want {
+		t.Errorf("Simulate() =\n%v\nwant\n%v", plan.String(), want)
+	}
+}
+
+func TestSimulate_Undecidable(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  check:
+    runs-on: ubuntu-latest
+    if: github.actor != 'dependabot[bot]'
+    steps:
+      - run: echo check
+  build:
+    needs: check
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo build
+  always:
+    needs: check
+    if: always()
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo always
+  failed:
+    needs: check
+    if: failure()
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo failed
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{Contexts: expr.ContextData{"github": expr.ContextData{"actor": expr.Unknown}}})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	tests := []struct {
+		job    string
+		status Status
+		reason string
+	}{
+		{"check", StatusUndecidable, "condition `github.actor != 'dependabot[bot]'` depends on values only known at runtime"},
+		{"build", StatusUndecidable, "needed job \"check\" may not run"},
+		{"always", StatusRun, ""},
+		{"failed", StatusUndecidable, "condition `failure()` depends on values only known at runtime"},
+	}
+	for _, tt := range tests {
+		j := plan.Job(tt.job)
+		if j.Status != tt.status || j.Reason != tt.reason {
+			t.Errorf("Simulate() job %s = %v (%s), want %v (%s)", tt.job, j.Status, j.Reason, tt.status, tt.reason)
+		}
+	}
+}
+
+func TestSimulate_Cycle(t *testing.T) {
+	w := &actionlint.Workflow{Jobs: map[string]*actionlint.Job{
+		"a": {ID: &actionlint.String{Value: "a"}, Needs: []*actionlint.String{{Value: "b"}}},
+		"b": {ID: &actionlint.String{Value: "b"}, Needs: []*actionlint.String{{Value: "a"}}},
+	}}
+
+	_, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err == nil || err.Error() != "dependency cycle between jobs a, b" {
+		t.Errorf("Simulate() error = %v, want dependency cycle", err)
+	}
+}
+
+func Test_parseExpression(t *testing.T) {
+	tests := []struct {
+		input  string
+		wantOk bool
+	}{
+		{"github.ref == 'main'", true},
+		{"${{ github.ref == 'main' }}", true},
+		{"  ${{ always() }}  ", true},
+		{"${{ a }} && ${{ b }}", false},
+		{"ref is ${{ github.ref }}", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, ok, err := parseExpression(tt.input)
+			if err != nil {
+				t.Fatalf("parseExpression() error = %v", err)
+			}
+			if ok != tt.wantOk || (ok && n == nil) {
+				t.Errorf("parseExpression() = %v, %v, want ok %v", n, ok, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSimulate_MatrixError(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    strategy:
+      matrix:
+        os: ${{ fromJSON('[]') }}
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo build
+  report:
+    needs: build
+    if: failure()
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo report
+  deploy:
+    needs: build
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo deploy
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"build: error (Error when evaluating 'strategy' for job 'build'. (Line: 6, Col: 7): Matrix vector 'os' does not contain any values)",
+		"deploy: skipped (needed job \"build\" fails)",
+		"report: run",
+		"  [0] Run echo report: run",
+		"",
+	}, "\n")
+
+	if plan.String() != want {
+		t.Errorf("Simulate() =\n%v\nwant\n%v", plan.String(), want)
+	}
+}
+
+func TestSimulate_Availability(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+env:
+  DEPLOY: 'true'
+jobs:
+  deploy:
+    if: env.DEPLOY == 'true'
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo deploy
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - if: env.DEPLOY == 'true' && runner.os == 'Linux'
+        run: echo build
+      - if: secrets.TOKEN != ''
+        run: echo token
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"build: run",
+		"  [0] Run echo build: run",
+		"  [1] Run echo token: error (could not evaluate condition `secrets.TOKEN != ''`: could not evaluate receiver: context \"secrets\" is not available in jobs.<job_id>.steps.if. Available contexts: github, needs, strategy, matrix, job, runner, env, vars, steps, inputs)",
+		"deploy: error (could not evaluate condition `env.DEPLOY == 'true'`: could not evaluate receiver: context \"env\" is not available in jobs.<job_id>.if. Available contexts: github, needs, vars, inputs)",
+		"",
+	}, "\n")
+	if plan.String() != want {
+		t.Errorf("Simulate() =\n%v\nwant\n%v", plan.String(), want)
+	}
+}
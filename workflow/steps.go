@@ -0,0 +1,163 @@
+package workflow
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// Step outcomes and conclusions
+const (
+	StepSuccess   = "success"
+	StepFailure   = "failure"
+	StepCancelled = "cancelled"
+	StepSkipped   = "skipped"
+)
+
+// Workflow keys of the steps of a job and of a composite action, for the availability of contexts
+const (
+	jobStepsKey       = "jobs.<job_id>.steps"
+	compositeStepsKey = "runs.steps"
+)
+
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// StepIDs returns the IDs of the steps of a job. Steps without an `id` get the ID the runner
+// generates: `__run` for scripts, and the action reference like `__actions_checkout` for actions.
+// Generated IDs used more than once get a suffix like `__run_2`.
+func StepIDs(steps []*actionlint.Step) []string {
+	ids := make([]string, len(steps))
+
+	used := map[string]bool{}
+	for i, s := range steps {
+		if s.ID != nil {
+			ids[i] = s.ID.Value
+			used[strings.ToLower(s.ID.Value)] = true
+		}
+	}
+
+	for i, s := range steps {
+		if s.ID != nil {
+			continue
+		}
+
+		base := "run"
+		if e, ok := s.Exec.(*actionlint.ExecAction); ok && e.Uses != nil {
+			ref := strings.SplitN(strings.TrimPrefix(e.Uses.Value, "docker://"), "@", 2)[0]
+			ref = strings.SplitN(ref, ":", 2)[0]
+			base = strings.Trim(invalidIDChars.ReplaceAllString(ref, "_"), "_")
+		}
+
+		id := "__" + base
+		for n := 2; used[strings.ToLower(id)]; n++ {
+			id = fmt.Sprintf("__%s_%d", base, n)
+		}
+
+		ids[i] = id
+		used[strings.ToLower(id)] = true
+	}
+
+	return ids
+}
+
+// StepResult is the result of a completed step
+type StepResult struct {
+	ID string
+
+	// Outputs are the outputs set by the step, or expr.Unknown
+	Outputs interface{}
+
+	// Outcome is the result of the step before `continue-on-error` is applied, Conclusion the
+	// result after. Both are one of the Step* constants, or expr.Unknown.
+	Outcome    interface{}
+	Conclusion interface{}
+}
+
+// StepsContext tracks the results of the steps of a job as they complete. It builds the `steps`
+// context and the status of the job checked by the status functions.
+type StepsContext struct {
+	results []*StepResult
+}
+
+// Complete records the result of a step. A failed outcome of a step with `continue-on-error` set
+// results in a successful conclusion. ContinueOnError may be expr.Unknown, the conclusion of a
+// failed step is unknown then.
+func (c *StepsContext) Complete(id string, outcome interface{}, continueOnError interface{}, outputs interface{}) *StepResult {
+	conclusion := outcome
+	if outcome == StepFailure {
+		switch continueOnError {
+		case true:
+			conclusion = StepSuccess
+		case false, nil:
+		default:
+			conclusion = expr.Unknown
+		}
+	}
+
+	if outputs == nil {
+		outputs = expr.ContextData{}
+	}
+
+	r := &StepResult{ID: id, Outputs: outputs, Outcome: outcome, Conclusion: conclusion}
+	c.results = append(c.results, r)
+
+	return r
+}
+
+// Context returns the `steps` context of the steps completed so far
+func (c *StepsContext) Context() expr.ContextData {
+	ctx := expr.ContextData{}
+	for _, r := range c.results {
+		ctx[r.ID] = expr.ContextData{
+			"outputs":    r.Outputs,
+			"outcome":    r.Outcome,
+			"conclusion": r.Conclusion,
+		}
+	}
+
+	return ctx
+}
+
+// Status returns the status of the job checked by the status functions, and the reason for a
+// status other than success. The job fails if the conclusion of a step is a failure. Steps with
+// an unknown conclusion are assumed to not fail, unless their outcome is a failure.
+func (c *StepsContext) Status() (expr.Status, string) {
+	status, reason := expr.StatusSuccess, ""
+
+	for _, r := range c.results {
+		switch {
+		case r.Conclusion == StepFailure:
+			return expr.StatusFailure, fmt.Sprintf("step %q fails", r.ID)
+
+		case r.Conclusion == StepCancelled:
+			return expr.StatusCancelled, fmt.Sprintf("step %q is cancelled", r.ID)
+
+		case r.Conclusion == expr.Unknown && r.Outcome == StepFailure && status == expr.StatusSuccess:
+			status, reason = expr.StatusUnknown, fmt.Sprintf("step %q may fail", r.ID)
+		}
+	}
+
+	return status, reason
+}
+
+// continueOnError evaluates the `continue-on-error` setting of a step at the given key, see
+// jobStepsKey
+func continueOnError(step *actionlint.Step, ctx expr.ContextData, key string) interface{} {
+	if step.ContinueOnError == nil {
+		return false
+	}
+
+	if step.ContinueOnError.Expression == nil {
+		return step.ContinueOnError.Value
+	}
+
+	v, err := evaluateString(step.ContinueOnError.Expression.Value, ctx, key+".continue-on-error")
+	if err != nil || v == expr.Unknown {
+		return expr.Unknown
+	}
+
+	return expr.NewEvaluationResult(v).Truthy()
+}
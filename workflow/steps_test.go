@@ -0,0 +1,145 @@
+package workflow
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func TestStepIDs(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v3
+      - run: make
+      - id: test
+        run: make test
+      - run: make lint
+      - uses: actions/checkout@v3
+        with:
+          repository: octo-org/docs
+      - uses: docker://alpine:3.15
+      - uses: ./.github/actions/deploy
+      - id: __run_3
+        run: echo taken
+      - run: echo
+`)
+
+	want := []string{"__actions_checkout", "__run", "test", "__run_2", "__actions_checkout_2", "__alpine", "__github_actions_deploy", "__run_3", "__run_4"}
+	if got := StepIDs(w.Jobs["build"].Steps); !reflect.DeepEqual(got, want) {
+		t.Errorf("StepIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestStepsContext(t *testing.T) {
+	tests := []struct {
+		name            string
+		outcome         interface{}
+		continueOnError interface{}
+		wantConclusion  interface{}
+		wantStatus      expr.Status
+	}{
+		{"success", StepSuccess, false, StepSuccess, expr.StatusSuccess},
+		{"skipped", StepSkipped, false, StepSkipped, expr.StatusSuccess},
+		{"failure", StepFailure, false, StepFailure, expr.StatusFailure},
+		{"continue on error", StepFailure, true, StepSuccess, expr.StatusSuccess},
+		{"unknown continue on error", StepFailure, expr.Unknown, expr.Unknown, expr.StatusUnknown},
+		{"unknown outcome", expr.Unknown, false, expr.Unknown, expr.StatusSuccess},
+		{"cancelled", StepCancelled, true, StepCancelled, expr.StatusCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &StepsContext{}
+			c.Complete("__run", StepSuccess, false, nil)
+			c.Complete("test", tt.outcome, tt.continueOnError, expr.ContextData{"result": "42"})
+
+			want := expr.ContextData{
+				"__run": expr.ContextData{"outputs": expr.ContextData{}, "outcome": StepSuccess, "conclusion": StepSuccess},
+				"test":  expr.ContextData{"outputs": expr.ContextData{"result": "42"}, "outcome": tt.outcome, "conclusion": tt.wantConclusion},
+			}
+			if got := c.Context(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Context() = %v, want %v", got, want)
+			}
+
+			if got, _ := c.Status(); got != tt.wantStatus {
+				t.Errorf("Status() = %v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSimulate_StepFailure(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - id: lint
+        if: secrets.LINT == 'true'
+        continue-on-error: true
+        run: make lint
+      - if: steps.lint.outcome == 'failure' && steps.lint.conclusion == 'success'
+        run: echo lint failed
+      - id: test
+        if: secrets.LINT == 'true'
+        run: make test
+      - run: make build
+      - if: failure()
+        run: echo report
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	got := []string{}
+	for _, s := range plan.Job("build").Runs[0].Steps {
+		got = append(got, s.ID+": "+s.Status.String())
+	}
+
+	want := []string{"lint: error", "__run: run", "test: error", "__run_2: skipped", "__run_3: run"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Simulate() steps = %v, want %v", got, want)
+	}
+
+	if r := plan.Job("build").Runs[0].Steps[3].Reason; !strings.Contains(r, `step "test" fails`) {
+		t.Errorf("Simulate() reason = %v", r)
+	}
+}
+
+func TestSimulate_HashFiles(t *testing.T) {
+	w := parseWorkflow(t, `
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - if: hashFiles('**/go.sum') != ''
+        run: go mod download
+      - env:
+          KEY: ${{ hashFiles('**/go.sum') }}
+        run: echo $KEY
+`)
+
+	plan, err := Simulate(w, &Event{Name: "push"}, Options{})
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	got := []string{}
+	for _, s := range plan.Job("build").Runs[0].Steps {
+		got = append(got, s.ID+": "+s.Status.String())
+	}
+
+	want := []string{"__run: undecidable", "__run_2: run"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Simulate() steps = %v, want %v", got, want)
+	}
+}
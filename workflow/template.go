@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+// evaluateString evaluates the expressions embedded in a workflow value. A value consisting of a
+// single `${{ }}` expression evaluates to the result of the expression, otherwise the results are
+// interpolated into the string. The result is expr.Unknown if any expression is unknown. Location
+// is the workflow key of the value, see expr.LookupAvailability.
+func evaluateString(s string, ctx expr.ContextData, location string) (interface{}, error) {
+	v, _, err := evaluateTemplate(s, ctx, location)
+	return v, err
+}
+
+// evaluateTemplate is like evaluateString, and also returns whether the value is derived from
+// secrets
+func evaluateTemplate(s string, ctx expr.ContextData, location string) (value interface{}, sensitive bool, err error) {
+	exprs, err := expr.ParseTemplate(s)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(exprs) == 0 {
+		return s, false, nil
+	}
+
+	opts := expr.EvaluateOptions{Location: location}
+
+	trimmed := strings.TrimSpace(s)
+	if len(exprs) == 1 && strings.HasPrefix(trimmed, "${{") && strings.HasSuffix(trimmed, "}}") && exprs[0].End-exprs[0].Offset == len(trimmed) {
+		r, err := expr.EvaluateWithOptions(exprs[0].Node, ctx, opts)
+		if err != nil {
+			return nil, false, err
+		}
+
+		return r.Value, r.Sensitive, nil
+	}
+
+	var b strings.Builder
+
+	offset := 0
+	unknown := false
+	for _, e := range exprs {
+		r, err := expr.EvaluateWithOptions(e.Node, ctx, opts)
+		if err != nil {
+			return nil, false, err
+		}
+
+		sensitive = sensitive || r.Sensitive
+
+		if r.IsUnknown() {
+			unknown = true
+			continue
+		}
+
+		b.WriteString(s[offset:e.Offset])
+		b.WriteString(r.CoerceString())
+		offset = e.End
+	}
+	b.WriteString(s[offset:])
+
+	if unknown {
+		return expr.Unknown, sensitive, nil
+	}
+
+	return b.String(), sensitive, nil
+}
@@ -0,0 +1,282 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+	"github.com/rhysd/actionlint"
+)
+
+// MaxChangedFiles is the number of changed files GitHub considers for path filters. Files after
+// the first 300 are ignored.
+const MaxChangedFiles = 300
+
+// defaultActivityTypes are the activity types triggering a workflow if `types` is not given, for
+// events that aren't triggered by all their activity types by default
+var defaultActivityTypes = map[string][]string{
+	"pull_request":        {"opened", "synchronize", "reopened"},
+	"pull_request_target": {"opened", "synchronize", "reopened"},
+}
+
+// TriggerMatch is the result of matching an event against the `on:` section of a workflow. Status
+// is StatusRun if the workflow is triggered, StatusSkipped if it isn't, and StatusUndecidable if
+// it depends on the changed files which aren't known.
+type TriggerMatch struct {
+	Status Status
+	Reason string
+}
+
+// MatchTrigger returns whether the event triggers the workflow. Branch, tag, and path filters and
+// activity types are matched against the payload of the event, e.g. `ref` for `push` events, and
+// the changed files of the event. An error is returned for invalid filters.
+func MatchTrigger(w *actionlint.Workflow, event *Event) (*TriggerMatch, error) {
+	for _, on := range w.On {
+		if on.EventName() != event.Name {
+			continue
+		}
+
+		switch e := on.(type) {
+		case *actionlint.WebhookEvent:
+			return matchWebhookEvent(e, event)
+
+		case *actionlint.RepositoryDispatchEvent:
+			return matchTypes(stringValues(e.Types), event), nil
+
+		default:
+			// Schedules, manual dispatches, and calls have no filters
+			return &TriggerMatch{Status: StatusRun}, nil
+		}
+	}
+
+	return &TriggerMatch{Status: StatusSkipped, Reason: fmt.Sprintf("workflow is not triggered by %q events", event.Name)}, nil
+}
+
+func matchWebhookEvent(e *actionlint.WebhookEvent, event *Event) (*TriggerMatch, error) {
+	for _, f := range [][2]string{{"branches", "branches-ignore"}, {"tags", "tags-ignore"}, {"paths", "paths-ignore"}} {
+		include, ignore := webhookFilter(e, f[0]), webhookFilter(e, f[1])
+		if include != nil && ignore != nil {
+			return nil, fmt.Errorf("%q event cannot use both %s and %s filters", event.Name, f[0], f[1])
+		}
+	}
+
+	types := stringValues(e.Types)
+	if types == nil {
+		types = defaultActivityTypes[event.Name]
+	}
+	if m := matchTypes(types, event); m.Status != StatusRun {
+		return m, nil
+	}
+
+	hasBranchFilters := e.Branches != nil || e.BranchesIgnore != nil
+	hasTagFilters := e.Tags != nil || e.TagsIgnore != nil
+
+	isTag := false
+
+	switch event.Name {
+	case "push":
+		ref, _ := event.Payload["ref"].(string)
+
+		switch {
+		case strings.HasPrefix(ref, "refs/tags/"):
+			isTag = true
+
+			if !hasTagFilters && hasBranchFilters {
+				return &TriggerMatch{Status: StatusSkipped, Reason: "only branch filters are defined, tag pushes don't trigger the workflow"}, nil
+			}
+
+			if m, err := matchRefFilters(e.Tags, e.TagsIgnore, strings.TrimPrefix(ref, "refs/tags/"), "tag"); m != nil || err != nil {
+				return m, err
+			}
+
+		case strings.HasPrefix(ref, "refs/heads/"):
+			if !hasBranchFilters && hasTagFilters {
+				return &TriggerMatch{Status: StatusSkipped, Reason: "only tag filters are defined, branch pushes don't trigger the workflow"}, nil
+			}
+
+			if m, err := matchRefFilters(e.Branches, e.BranchesIgnore, strings.TrimPrefix(ref, "refs/heads/"), "branch"); m != nil || err != nil {
+				return m, err
+			}
+
+		default:
+			if hasBranchFilters || hasTagFilters {
+				return &TriggerMatch{Status: StatusUndecidable, Reason: "ref of the push is unknown"}, nil
+			}
+		}
+
+	case "pull_request", "pull_request_target":
+		base, _ := lookupPath(event.Payload, "pull_request", "base", "ref").(string)
+
+		if hasBranchFilters && base == "" {
+			return &TriggerMatch{Status: StatusUndecidable, Reason: "base branch of the pull request is unknown"}, nil
+		}
+
+		if m, err := matchRefFilters(e.Branches, e.BranchesIgnore, base, "branch"); m != nil || err != nil {
+			return m, err
+		}
+
+	case "workflow_run":
+		if workflows := stringValues(e.Workflows); workflows != nil {
+			name, _ := lookupPath(event.Payload, "workflow_run", "name").(string)
+			if !containsFold(workflows, name) {
+				return &TriggerMatch{Status: StatusSkipped, Reason: fmt.Sprintf("workflow %q is not one of the workflows %s", name, strings.Join(workflows, ", "))}, nil
+			}
+		}
+
+		head, _ := lookupPath(event.Payload, "workflow_run", "head_branch").(string)
+		if m, err := matchRefFilters(e.Branches, e.BranchesIgnore, head, "branch"); m != nil || err != nil {
+			return m, err
+		}
+	}
+
+	// Path filters don't apply to tag pushes
+	if (event.Name == "push" && !isTag) || event.Name == "pull_request" || event.Name == "pull_request_target" {
+		return matchPathFilters(e, event)
+	}
+
+	return &TriggerMatch{Status: StatusRun}, nil
+}
+
+// webhookFilter returns the filter with the given name, nil if it's not defined
+func webhookFilter(e *actionlint.WebhookEvent, name string) []*actionlint.String {
+	switch name {
+	case "branches":
+		return e.Branches
+	case "branches-ignore":
+		return e.BranchesIgnore
+	case "tags":
+		return e.Tags
+	case "tags-ignore":
+		return e.TagsIgnore
+	case "paths":
+		return e.Paths
+	default:
+		return e.PathsIgnore
+	}
+}
+
+// matchTypes matches the `action` of the payload against the activity types. All types match if
+// types is nil.
+func matchTypes(types []string, event *Event) *TriggerMatch {
+	action, _ := event.Payload["action"].(string)
+	if types == nil || action == "" || containsFold(types, action) {
+		return &TriggerMatch{Status: StatusRun}
+	}
+
+	return &TriggerMatch{Status: StatusSkipped, Reason: fmt.Sprintf("activity type %q is not one of %s", action, strings.Join(types, ", "))}
+}
+
+// matchRefFilters matches a branch or tag name against an include or ignore filter. It returns
+// nil if the name passes the filters.
+func matchRefFilters(include, ignore []*actionlint.String, name string, kind string) (*TriggerMatch, error) {
+	plural := "branches"
+	if kind == "tag" {
+		plural = "tags"
+	}
+
+	if include != nil {
+		m, err := MatchPatterns(stringValues(include), name)
+		if err != nil {
+			return nil, err
+		}
+		if !m {
+			return &TriggerMatch{Status: StatusSkipped, Reason: fmt.Sprintf("%s %q does not match %s filter", kind, name, plural)}, nil
+		}
+	}
+
+	if ignore != nil {
+		m, err := MatchPatterns(stringValues(ignore), name)
+		if err != nil {
+			return nil, err
+		}
+		if m {
+			return &TriggerMatch{Status: StatusSkipped, Reason: fmt.Sprintf("%s %q matches %s-ignore filter", kind, name, plural)}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// matchPathFilters matches the changed files against `paths` or `paths-ignore`. Only the first
+// MaxChangedFiles files are considered.
+func matchPathFilters(e *actionlint.WebhookEvent, event *Event) (*TriggerMatch, error) {
+	if e.Paths == nil && e.PathsIgnore == nil {
+		return &TriggerMatch{Status: StatusRun}, nil
+	}
+
+	if event.ChangedFiles == nil {
+		return &TriggerMatch{Status: StatusUndecidable, Reason: "path filters depend on the changed files, which are unknown"}, nil
+	}
+
+	files := event.ChangedFiles
+	if len(files) > MaxChangedFiles {
+		files = files[:MaxChangedFiles]
+	}
+
+	if e.Paths != nil {
+		patterns := stringValues(e.Paths)
+		for _, f := range files {
+			m, err := MatchPatterns(patterns, f)
+			if err != nil {
+				return nil, err
+			}
+			if m {
+				return &TriggerMatch{Status: StatusRun}, nil
+			}
+		}
+
+		return &TriggerMatch{Status: StatusSkipped, Reason: "no changed file matches paths filter"}, nil
+	}
+
+	patterns := stringValues(e.PathsIgnore)
+	for _, f := range files {
+		m, err := MatchPatterns(patterns, f)
+		if err != nil {
+			return nil, err
+		}
+		if !m {
+			return &TriggerMatch{Status: StatusRun}, nil
+		}
+	}
+
+	return &TriggerMatch{Status: StatusSkipped, Reason: "all changed files match paths-ignore filter"}, nil
+}
+
+func stringValues(s []*actionlint.String) []string {
+	if s == nil {
+		return nil
+	}
+
+	r := make([]string, 0, len(s))
+	for _, v := range s {
+		r = append(r, v.Value)
+	}
+
+	return r
+}
+
+func containsFold(s []string, v string) bool {
+	for _, e := range s {
+		if strings.EqualFold(e, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookupPath returns the value at the given path in the payload, or nil
+func lookupPath(payload expr.ContextData, path ...string) interface{} {
+	var v interface{} = payload
+
+	for _, p := range path {
+		obj, ok := v.(expr.ContextData)
+		if !ok {
+			return nil
+		}
+
+		v = obj[p]
+	}
+
+	return v
+}
@@ -0,0 +1,371 @@
+package workflow
+
+import (
+	"fmt"
+	"testing"
+
+	expr "github.com/cschleiden/actionlint-interpreter"
+)
+
+func pushEvent(ref string, files ...string) *Event {
+	return &Event{Name: "push", Payload: expr.ContextData{"ref": ref}, ChangedFiles: files}
+}
+
+func pullRequestEvent(action string, base string, files ...string) *Event {
+	return &Event{
+		Name: "pull_request",
+		Payload: expr.ContextData{
+			"action":       action,
+			"pull_request": expr.ContextData{"base": expr.ContextData{"ref": base}},
+		},
+		ChangedFiles: files,
+	}
+}
+
+func TestMatchTrigger(t *testing.T) {
+	many := make([]string, 301)
+	for i := range many {
+		many[i] = fmt.Sprintf("src/file%d.go", i)
+	}
+	many[300] = "docs/README.md"
+
+	tests := []struct {
+		name       string
+		on         string
+		event      *Event
+		want       Status
+		wantReason string
+	}{
+		{
+			name:  "event name",
+			on:    "push",
+			event: pushEvent("refs/heads/main"),
+			want:  StatusRun,
+		},
+		{
+			name:       "other event",
+			on:         "push",
+			event:      pullRequestEvent("opened", "main"),
+			want:       StatusSkipped,
+			wantReason: `workflow is not triggered by "pull_request" events`,
+		},
+		{
+			name:  "event list",
+			on:    "[push, pull_request]",
+			event: pullRequestEvent("opened", "main"),
+			want:  StatusRun,
+		},
+		{
+			name: "branches",
+			on: `
+  push:
+    branches: [main, 'releases/**']`,
+			event: pushEvent("refs/heads/releases/v1"),
+			want:  StatusRun,
+		},
+		{
+			name: "branches no match",
+			on: `
+  push:
+    branches: [main]`,
+			event:      pushEvent("refs/heads/feature"),
+			want:       StatusSkipped,
+			wantReason: `branch "feature" does not match branches filter`,
+		},
+		{
+			name: "branches negation",
+			on: `
+  push:
+    branches: ['releases/**', '!releases/**-alpha']`,
+			event:      pushEvent("refs/heads/releases/v1-alpha"),
+			want:       StatusSkipped,
+			wantReason: `branch "releases/v1-alpha" does not match branches filter`,
+		},
+		{
+			name: "branches-ignore",
+			on: `
+  push:
+    branches-ignore: ['mona/octocat', 'releases/**-alpha']`,
+			event:      pushEvent("refs/heads/releases/beta/3-alpha"),
+			want:       StatusSkipped,
+			wantReason: `branch "releases/beta/3-alpha" matches branches-ignore filter`,
+		},
+		{
+			name: "branches-ignore no match",
+			on: `
+  push:
+    branches-ignore: ['mona/octocat']`,
+			event: pushEvent("refs/heads/main"),
+			want:  StatusRun,
+		},
+		{
+			name: "tags",
+			on: `
+  push:
+    tags: ['v[0-9]+.*']`,
+			event: pushEvent("refs/tags/v1.2"),
+			want:  StatusRun,
+		},
+		{
+			name: "tags-ignore",
+			on: `
+  push:
+    tags-ignore: ['v1.*']`,
+			event:      pushEvent("refs/tags/v1.2"),
+			want:       StatusSkipped,
+			wantReason: `tag "v1.2" matches tags-ignore filter`,
+		},
+		{
+			name: "only branch filters skip tags",
+			on: `
+  push:
+    branches: ['**']`,
+			event:      pushEvent("refs/tags/v1"),
+			want:       StatusSkipped,
+			wantReason: "only branch filters are defined, tag pushes don't trigger the workflow",
+		},
+		{
+			name: "only tag filters skip branches",
+			on: `
+  push:
+    tags: ['**']`,
+			event:      pushEvent("refs/heads/main"),
+			want:       StatusSkipped,
+			wantReason: "only tag filters are defined, branch pushes don't trigger the workflow",
+		},
+		{
+			name: "branch and tag filters",
+			on: `
+  push:
+    branches: [main]
+    tags: ['v*']`,
+			event: pushEvent("refs/tags/v2"),
+			want:  StatusRun,
+		},
+		{
+			name: "empty branches filter",
+			on: `
+  push:
+    branches: []
+    tags: ['v*']`,
+			event:      pushEvent("refs/heads/main"),
+			want:       StatusSkipped,
+			wantReason: `branch "main" does not match branches filter`,
+		},
+		{
+			name: "paths",
+			on: `
+  push:
+    paths: ['**.js']`,
+			event: pushEvent("refs/heads/main", "README.md", "src/app.js"),
+			want:  StatusRun,
+		},
+		{
+			name: "paths no match",
+			on: `
+  push:
+    paths: ['**.js']`,
+			event:      pushEvent("refs/heads/main", "README.md"),
+			want:       StatusSkipped,
+			wantReason: "no changed file matches paths filter",
+		},
+		{
+			name: "paths negation",
+			on: `
+  push:
+    paths: ['sub-project/**', '!sub-project/docs/**']`,
+			event:      pushEvent("refs/heads/main", "sub-project/docs/README.md"),
+			want:       StatusSkipped,
+			wantReason: "no changed file matches paths filter",
+		},
+		{
+			name: "paths-ignore",
+			on: `
+  push:
+    paths-ignore: ['docs/**']`,
+			event:      pushEvent("refs/heads/main", "docs/README.md", "docs/a.md"),
+			want:       StatusSkipped,
+			wantReason: "all changed files match paths-ignore filter",
+		},
+		{
+			name: "paths-ignore with other files",
+			on: `
+  push:
+    paths-ignore: ['docs/**']`,
+			event: pushEvent("refs/heads/main", "docs/README.md", "main.go"),
+			want:  StatusRun,
+		},
+		{
+			name: "paths unknown changed files",
+			on: `
+  push:
+    paths: ['**.js']`,
+			event:      pushEvent("refs/heads/main"),
+			want:       StatusUndecidable,
+			wantReason: "path filters depend on the changed files, which are unknown",
+		},
+		{
+			name: "paths ignored for tags",
+			on: `
+  push:
+    tags: ['v*']
+    paths: ['**.js']`,
+			event: pushEvent("refs/tags/v1", "README.md"),
+			want:  StatusRun,
+		},
+		{
+			name: "paths and branches",
+			on: `
+  push:
+    branches: [main]
+    paths: ['**.js']`,
+			event:      pushEvent("refs/heads/dev", "app.js"),
+			want:       StatusSkipped,
+			wantReason: `branch "dev" does not match branches filter`,
+		},
+		{
+			name: "paths limited to 300 files",
+			on: `
+  push:
+    paths: ['docs/**']`,
+			event:      pushEvent("refs/heads/main", many...),
+			want:       StatusSkipped,
+			wantReason: "no changed file matches paths filter",
+		},
+		{
+			name:       "pull_request default types",
+			on:         "pull_request",
+			event:      pullRequestEvent("labeled", "main"),
+			want:       StatusSkipped,
+			wantReason: `activity type "labeled" is not one of opened, synchronize, reopened`,
+		},
+		{
+			name: "pull_request types",
+			on: `
+  pull_request:
+    types: [labeled]`,
+			event: pullRequestEvent("labeled", "main"),
+			want:  StatusRun,
+		},
+		{
+			name: "pull_request base branch",
+			on: `
+  pull_request:
+    branches: ['releases/**']`,
+			event:      pullRequestEvent("opened", "main"),
+			want:       StatusSkipped,
+			wantReason: `branch "main" does not match branches filter`,
+		},
+		{
+			name: "pull_request paths",
+			on: `
+  pull_request:
+    branches: [main]
+    paths: ['src/**']`,
+			event: pullRequestEvent("synchronize", "main", "src/a/b.go"),
+			want:  StatusRun,
+		},
+		{
+			name: "issues types",
+			on: `
+  issues:
+    types: [opened, edited]`,
+			event:      &Event{Name: "issues", Payload: expr.ContextData{"action": "closed"}},
+			want:       StatusSkipped,
+			wantReason: `activity type "closed" is not one of opened, edited`,
+		},
+		{
+			name:  "issues all types",
+			on:    "issues",
+			event: &Event{Name: "issues", Payload: expr.ContextData{"action": "closed"}},
+			want:  StatusRun,
+		},
+		{
+			name: "workflow_run",
+			on: `
+  workflow_run:
+    workflows: [CI]
+    types: [completed]
+    branches: [main]`,
+			event: &Event{Name: "workflow_run", Payload: expr.ContextData{
+				"action":       "completed",
+				"workflow_run": expr.ContextData{"name": "CI", "head_branch": "main"},
+			}},
+			want: StatusRun,
+		},
+		{
+			name: "workflow_run other workflow",
+			on: `
+  workflow_run:
+    workflows: [CI]`,
+			event: &Event{Name: "workflow_run", Payload: expr.ContextData{
+				"action":       "completed",
+				"workflow_run": expr.ContextData{"name": "Release", "head_branch": "main"},
+			}},
+			want:       StatusSkipped,
+			wantReason: `workflow "Release" is not one of the workflows CI`,
+		},
+		{
+			name: "repository_dispatch types",
+			on: `
+  repository_dispatch:
+    types: [deploy]`,
+			event:      &Event{Name: "repository_dispatch", Payload: expr.ContextData{"action": "test"}},
+			want:       StatusSkipped,
+			wantReason: `activity type "test" is not one of deploy`,
+		},
+		{
+			name:  "workflow_dispatch",
+			on:    "workflow_dispatch",
+			event: &Event{Name: "workflow_dispatch"},
+			want:  StatusRun,
+		},
+		{
+			name: "schedule",
+			on: `
+  schedule:
+    - cron: '0 0 * * *'`,
+			event: &Event{Name: "schedule"},
+			want:  StatusRun,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := parseWorkflow(t, `
+on: `+tt.on+`
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+			got, err := MatchTrigger(w, tt.event)
+			if err != nil {
+				t.Fatalf("MatchTrigger() error = %v", err)
+			}
+			if got.Status != tt.want || got.Reason != tt.wantReason {
+				t.Errorf("MatchTrigger() = %v (%s), want %v (%s)", got.Status, got.Reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestMatchTrigger_InvalidFilters(t *testing.T) {
+	w := parseWorkflow(t, `
+on:
+  push:
+    branches: [main]
+    branches-ignore: [dev]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo
+`)
+
+	_, err := MatchTrigger(w, pushEvent("refs/heads/main"))
+	if err == nil || err.Error() != `"push" event cannot use both branches and branches-ignore filters` {
+		t.Errorf("MatchTrigger() error = %v", err)
+	}
+}